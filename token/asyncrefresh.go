@@ -0,0 +1,54 @@
+package token
+
+import (
+	"context"
+	"time"
+)
+
+// WithAsyncRefresh refreshes tokens in the background once they are within the expiry buffer, serving the cached token
+// to callers meanwhile rather than blocking them on the refresh. Fetches only block on a refresh if the cached token has
+// expired, would expire within the min usable lifetime, has changed at source or been revoked, or the refresh predicate
+// requires a refresh. Forced refreshes and fetches with the cache disabled always block.
+func WithAsyncRefresh() Option {
+	return func(c *config) { c.asyncRefresh = true }
+}
+
+// refreshAsync starts a refresh in the background, unless one is running or refreshes are held back by the circuit
+// breaker or min refresh interval, and reports whether the cached token can be served meanwhile. It must be called while
+// the Fetcher is locked.
+func (f *Fetcher) refreshAsync(ctx context.Context, timeout time.Duration) bool {
+	now := f.clock.Now()
+	if !f.config.asyncRefresh || !f.token.Valid(now.Add(-f.config.clockSkew), 0) ||
+		f.config.belowMinUsableLifetime(f.token, now) {
+		return false
+	}
+	if f.refreshing || f.refreshHeldBack() {
+		return true
+	}
+
+	f.refreshing = true
+	go f.backgroundRefresh(context.WithoutCancel(ctx), f.config.refreshQueue, f.adapterCall(), timeout)
+	return true
+}
+
+// backgroundRefresh makes the adapter call in the refresh queue without holding the lock, so fetches continue to be
+// served the cached token, then caches the result unless the Fetcher has been closed. It mustn't read the config, which
+// may be changed while unlocked, so is passed the parts it needs.
+func (f *Fetcher) backgroundRefresh(ctx context.Context, queue *RefreshQueue, call adapterCall, timeout time.Duration) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := f.clock.Now()
+	t, err := queue.fetch(ctx, call.fetch)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refreshing = false
+	if f.closed {
+		return
+	}
+	_, _ = f.refreshed(ctx, start, t, err)
+}
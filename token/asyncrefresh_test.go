@@ -0,0 +1,204 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestWithAsyncRefresh(t *testing.T) {
+	t.Run("WithAsyncRefresh sets async refresh", func(t *testing.T) {
+		assert.Truef(t, newConfig(WithAsyncRefresh()).asyncRefresh, "WithAsyncRefresh()")
+	})
+}
+
+func TestFetcher_Fetch_asyncRefresh(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	cached := Token{AccessToken: "old-token-123", Expiry: now.Add(30 * time.Second)}
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+
+	newAsyncFetcher := func(m *mockAdapter, c config, token Token) *Fetcher {
+		c.tokenExpiryBuffer = time.Minute
+		c.asyncRefresh = true
+		return &Fetcher{config: c, clock: clock.NewFixed(now), adapter: m, token: token}
+	}
+	waitRefreshed := func(f *Fetcher) {
+		assert.Eventually(t, func() bool {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			return !f.refreshing
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("token within expiry buffer, serves cached token while refreshing in background", func(t *testing.T) {
+		release := make(chan struct{})
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Run(func(mock.Arguments) { <-release }).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{}, cached)
+
+		for range 3 {
+			got, meta, err := f.FetchWithMeta(context.Background())
+			assert.NoErrorf(t, err, "FetchWithMeta()")
+			assert.Equalf(t, cached, got, "FetchWithMeta()")
+			assert.Falsef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		}
+
+		close(release)
+		waitRefreshed(f)
+		got, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within expiry buffer, background refresh fails, keeps cached token", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+		f := newAsyncFetcher(m, config{}, cached)
+
+		got, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, cached, got, "Fetch()")
+
+		waitRefreshed(f)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		assert.Equalf(t, cached, f.token, "Fetch() token")
+		assert.Errorf(t, f.lastRefreshErr, "Fetch() last refresh error")
+		assert.Equalf(t, 1, f.consecutiveFailures, "Fetch() consecutive failures")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within expiry buffer, fetcher closed during background refresh, discards token", func(t *testing.T) {
+		release := make(chan struct{})
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Run(func(mock.Arguments) { <-release }).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{}, cached)
+
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.NoErrorf(t, f.Close(), "Close()")
+
+		close(release)
+		waitRefreshed(f)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		assert.Equalf(t, Token{}, f.token, "Fetch() token")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within min usable lifetime, refreshes before returning", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{minUsableLifetime: time.Minute}, cached)
+
+		got, meta, err := f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, tok, got, "FetchWithMeta()")
+		assert.Truef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within expiry buffer, source changed, refreshes before returning", func(t *testing.T) {
+		m := new(mockChangeDetectingAdapter)
+		m.On("Changed", mock.Anything).Return(true, nil).Once()
+		m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		f := newAsyncFetcher(&m.mockAdapter, config{changeCheckInterval: time.Minute}, cached)
+		f.adapter = m
+
+		got, meta, err := f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, tok, got, "FetchWithMeta()")
+		assert.Truef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		assert.Falsef(t, f.refreshing, "FetchWithMeta() refreshing")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token beyond expiry buffer, source changed, refreshes before returning", func(t *testing.T) {
+		m := new(mockChangeDetectingAdapter)
+		m.On("Changed", mock.Anything).Return(true, nil).Once()
+		m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		f := newAsyncFetcher(&m.mockAdapter, config{changeCheckInterval: time.Minute}, Token{AccessToken: "old-token-123", Expiry: now.Add(time.Hour)})
+		f.adapter = m
+
+		got, meta, err := f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, tok, got, "FetchWithMeta()")
+		assert.Truef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("refresh predicate returns true, refreshes before returning", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{refreshPredicate: func(current Token, _ time.Time) bool {
+			return current.AccessToken == cached.AccessToken
+		}}, cached)
+
+		got, meta, err := f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, tok, got, "FetchWithMeta()")
+		assert.Truef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		assert.Falsef(t, f.refreshing, "FetchWithMeta() refreshing")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token expired, refreshes before returning", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{}, Token{AccessToken: "old-token-123", Expiry: now.Add(-time.Second)})
+
+		got, meta, err := f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, tok, got, "FetchWithMeta()")
+		assert.Truef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within expiry buffer, force refresh, refreshes before returning", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{}, cached)
+
+		got, err := f.Fetch(context.Background(), ForceRefresh())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within expiry buffer, circuit open, serves cached token without refreshing", func(t *testing.T) {
+		m := new(mockAdapter)
+		f := newAsyncFetcher(m, config{circuitFailures: 1}, cached)
+		f.circuitOpenUntil = now.Add(time.Minute)
+
+		got, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, cached, got, "Fetch()")
+		assert.Falsef(t, f.refreshing, "Fetch() refreshing")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("token within expiry buffer, expiry buffer set during background refresh, doesn't race", func(t *testing.T) {
+		release := make(chan struct{})
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Run(func(mock.Arguments) { <-release }).Return(tok, nil).Once()
+		f := newAsyncFetcher(m, config{postProcessor: func(t Token) (Token, error) { return t, nil }}, cached)
+
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+
+		set := make(chan struct{})
+		go func() {
+			defer close(set)
+			f.SetTokenExpiryBuffer(2 * time.Minute)
+		}()
+		close(release)
+		<-set
+		waitRefreshed(f)
+		m.AssertExpectations(t)
+	})
+}
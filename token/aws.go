@@ -0,0 +1,480 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const awsCurrentVersionStage = "AWSCURRENT"
+
+// WithSecretsManagerRotationExpiry derives the expiry date of tokens stored in AWS Secrets Manager without one from the
+// rotation schedule of the secret, so refreshes align with rotation. The created date is derived from the last rotation
+// if missing. It costs a DescribeSecret call for each version of the secret fetched.
+func WithSecretsManagerRotationExpiry() Option {
+	return func(c *config) { c.rotationExpiry = true }
+}
+
+// WithSecretsManagerOptions sets functions applied to the options of each AWS Secrets Manager API call, e.g. to set a
+// custom retryer or endpoint resolver without rebuilding the client
+func WithSecretsManagerOptions(optFns ...func(*secretsmanager.Options)) Option {
+	return func(c *config) { c.smOptFns = append(c.smOptFns, optFns...) }
+}
+
+// WithMaxClockDrift sets a callback invoked when the local clock drifts from the clock of AWS by more than maxDrift, as
+// measured from the Date header of Secrets Manager responses. The drift is positive if the local clock is ahead. It is
+// a diagnostic for hosts with unreliable clocks, so doesn't affect refreshes; as the header has a resolution of a
+// second, maxDrift should be at least a few seconds.
+func WithMaxClockDrift(maxDrift time.Duration, fn func(drift time.Duration)) Option {
+	return func(c *config) { c.clockDrift = clockDriftCheck{maxDrift: maxDrift, fn: fn} }
+}
+
+// clockDriftCheck reports drift between the local clock and the clock of AWS exceeding maxDrift
+type clockDriftCheck struct {
+	maxDrift time.Duration
+	fn       func(drift time.Duration)
+}
+
+func (c clockDriftCheck) check(metadata middleware.Metadata) {
+	if c.fn == nil {
+		return
+	}
+	if skew, ok := awsmiddleware.GetAttemptSkew(metadata); ok {
+		c.report(-skew)
+	}
+}
+
+func (c clockDriftCheck) report(drift time.Duration) {
+	if drift > c.maxDrift || drift < -c.maxDrift {
+		c.fn(drift)
+	}
+}
+
+// NewAWSSecretsManagerFetcher returns a new Fetcher with the awsSecretsManagerClient Adapter
+func NewAWSSecretsManagerFetcher(smClient *secretsmanager.Client, smKey string, opts ...Option) *Fetcher {
+	c := newConfig(opts...)
+	return newFetcher(&awsSecretsManagerAdapter{
+		client:         smClient,
+		key:            smKey,
+		decoder:        c.decoder,
+		rotationExpiry: c.rotationExpiry,
+		clockDrift:     c.clockDrift,
+		smOptFns:       c.smOptFns,
+	},
+		c,
+	)
+}
+
+// NewAWSSecretsManagerAssumeRoleFetcher returns a new Fetcher with the awsSecretsManagerClient Adapter, reading the
+// secret with the credentials of roleARN assumed through stsClient, e.g. for secrets in another account. The assumed
+// role credentials are cached and refreshed before they expire. Failures to assume the role are wrapped in
+// ErrAssumeRole, to distinguish them from failures to read the secret.
+func NewAWSSecretsManagerAssumeRoleFetcher(cfg aws.Config, stsClient stscreds.AssumeRoleAPIClient, roleARN string, smKey string, opts ...Option) *Fetcher {
+	cfg = cfg.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(assumeRoleProvider{
+		provider: stscreds.NewAssumeRoleProvider(stsClient, roleARN),
+		roleARN:  roleARN,
+	})
+	return NewAWSSecretsManagerFetcher(secretsmanager.NewFromConfig(cfg), smKey, opts...)
+}
+
+// KeyResolver resolves the key of a secret at fetch time, e.g. from service discovery
+type KeyResolver func(ctx context.Context) (string, error)
+
+// NewAWSSecretsManagerKeyResolverFetcher returns a new Fetcher with the awsSecretsManagerClient Adapter, reading the
+// secret with the key returned by resolveKey on each fetch, for secrets whose key isn't known until runtime
+func NewAWSSecretsManagerKeyResolverFetcher(smClient *secretsmanager.Client, resolveKey KeyResolver, opts ...Option) *Fetcher {
+	c := newConfig(opts...)
+	return newFetcher(&awsSecretsManagerAdapter{
+		client:         smClient,
+		resolveKey:     resolveKey,
+		decoder:        c.decoder,
+		rotationExpiry: c.rotationExpiry,
+		clockDrift:     c.clockDrift,
+		smOptFns:       c.smOptFns,
+	},
+		c,
+	)
+}
+
+// assumeRoleProvider wraps the errors of an assume role credentials provider in ErrAssumeRole
+type assumeRoleProvider struct {
+	provider aws.CredentialsProvider
+	roleARN  string
+}
+
+func (p assumeRoleProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("%w %s: %w", ErrAssumeRole, p.roleARN, err)
+	}
+	return creds, nil
+}
+
+type awsSecretsManagerClient interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+}
+type awsSecretsManagerAdapter struct {
+	client         awsSecretsManagerClient
+	key            string
+	resolveKey     KeyResolver
+	decoder        secretDecoder
+	rotationExpiry bool
+	clockDrift     clockDriftCheck
+	smOptFns       []func(*secretsmanager.Options)
+
+	mu                sync.Mutex
+	versionID         string
+	rotation          rotationSchedule
+	rotationVersionID string
+}
+
+// rotationSchedule is the rotation metadata of a secret
+type rotationSchedule struct {
+	lastRotated  time.Time
+	nextRotation time.Time
+}
+
+func (a *awsSecretsManagerAdapter) Fetch(ctx context.Context) (Token, error) {
+	key, err := a.secretKey(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	raw, versionID, err := a.secretValue(ctx, key)
+	if err != nil {
+		return Token{}, err
+	}
+
+	t, err := a.decoder.decode(raw)
+	if err != nil {
+		return Token{}, sourceError("parse token", secretsManagerSource(key), err)
+	}
+	if a.rotationExpiry && (t.Expiry.IsZero() || t.CreatedAt.IsZero()) {
+		r, err := a.rotationSchedule(ctx, key, versionID)
+		if err != nil {
+			return Token{}, err
+		}
+		if t.Expiry.IsZero() {
+			t.Expiry = r.nextRotation
+		}
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = r.lastRotated
+		}
+	}
+
+	a.setVersionID(versionID)
+	return t, nil
+}
+
+// rotationSchedule returns the rotation schedule of the secret, describing the secret only once per version. The next
+// rotation is derived from the rotation rules if Secrets Manager doesn't report it.
+func (a *awsSecretsManagerAdapter) rotationSchedule(ctx context.Context, key string, versionID string) (rotationSchedule, error) {
+	a.mu.Lock()
+	if versionID != "" && versionID == a.rotationVersionID {
+		defer a.mu.Unlock()
+		return a.rotation, nil
+	}
+	a.mu.Unlock()
+
+	out, err := a.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(key),
+	}, a.smOptFns...)
+	if err != nil {
+		return rotationSchedule{}, sourceError("describe secret", secretsManagerSource(key), classifyError(err))
+	}
+
+	r := rotationSchedule{
+		lastRotated:  aws.ToTime(out.LastRotatedDate),
+		nextRotation: aws.ToTime(out.NextRotationDate),
+	}
+	if r.nextRotation.IsZero() && !r.lastRotated.IsZero() && out.RotationRules != nil && out.RotationRules.AutomaticallyAfterDays != nil {
+		r.nextRotation = r.lastRotated.AddDate(0, 0, int(*out.RotationRules.AutomaticallyAfterDays))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotation = r
+	a.rotationVersionID = versionID
+	return r, nil
+}
+
+// secretKey returns the key of the secret, resolving it if the adapter has a KeyResolver
+func (a *awsSecretsManagerAdapter) secretKey(ctx context.Context) (string, error) {
+	if a.resolveKey == nil {
+		return a.key, nil
+	}
+	key, err := a.resolveKey(ctx)
+	if err != nil {
+		return "", sourceError("resolve key", "secretsmanager", err)
+	}
+	return key, nil
+}
+
+func (a *awsSecretsManagerAdapter) secretValue(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(key),
+	}, a.smOptFns...)
+	if err != nil {
+		return nil, "", sourceError("fetch token", secretsManagerSource(key), classifyError(err))
+	}
+	a.clockDrift.check(out.ResultMetadata)
+
+	// Binary secrets are base64 decoded by the SDK
+	raw := out.SecretBinary
+	if out.SecretString != nil {
+		raw = []byte(*out.SecretString)
+	}
+	if len(raw) == 0 {
+		return nil, "", sourceError("fetch token", secretsManagerSource(key), ErrEmptySecret)
+	}
+	return raw, aws.ToString(out.VersionId), nil
+}
+
+// classifyError wraps errors for a missing secret or denied access in ErrSecretNotFound or ErrAccessDenied, so the
+// misconfiguration can be diagnosed. Errors sending the request or server errors are wrapped in ErrRegionUnavailable.
+func classifyError(err error) error {
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return fmt.Errorf("%w: %w", ErrRegionUnavailable, err)
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ResourceNotFoundException":
+			return fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+		case "AccessDeniedException":
+			return fmt.Errorf("%w: %w", ErrAccessDenied, err)
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.HTTPStatusCode() >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %w", ErrRegionUnavailable, err)
+	}
+	return err
+}
+
+// secretsManagerSource returns the identifier of a secret in errors
+func secretsManagerSource(key string) string {
+	return "secretsmanager[" + key + "]"
+}
+
+func (a *awsSecretsManagerAdapter) setVersionID(versionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.versionID = versionID
+}
+
+// Changed reports whether the current version of the secret differs from the version last fetched
+func (a *awsSecretsManagerAdapter) Changed(ctx context.Context) (bool, error) {
+	key, err := a.secretKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := a.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(key),
+	}, a.smOptFns...)
+	if err != nil {
+		return false, sourceError("describe secret", secretsManagerSource(key), classifyError(err))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, stages := range out.VersionIdsToStages {
+		if slices.Contains(stages, awsCurrentVersionStage) {
+			return id != a.versionID, nil
+		}
+	}
+	return false, nil
+}
+
+// NewAWSSecretsManagerMultiRegionFetcher returns a new Fetcher reading a secret replicated to several regions, with a
+// client for each region in order of preference. Fetches fail over to the next region when a region is unavailable,
+// and start from the last region to succeed, so a dead region isn't probed on every fetch. Errors other than
+// ErrRegionUnavailable, e.g. ErrSecretNotFound, are returned without failing over.
+func NewAWSSecretsManagerMultiRegionFetcher(smClients []*secretsmanager.Client, smKey string, opts ...Option) *Fetcher {
+	c := newConfig(opts...)
+	regions := make([]*awsSecretsManagerAdapter, len(smClients))
+	for i, client := range smClients {
+		regions[i] = &awsSecretsManagerAdapter{
+			client:         client,
+			key:            smKey,
+			decoder:        c.decoder,
+			rotationExpiry: c.rotationExpiry,
+			clockDrift:     c.clockDrift,
+			smOptFns:       c.smOptFns,
+		}
+	}
+	return newFetcher(&awsSecretsManagerRegionsAdapter{regions: regions}, c)
+}
+
+// awsSecretsManagerRegionsAdapter fails over between adapters reading replicas of a secret in different regions
+type awsSecretsManagerRegionsAdapter struct {
+	regions []*awsSecretsManagerAdapter
+
+	mu      sync.Mutex
+	current int
+}
+
+func (a *awsSecretsManagerRegionsAdapter) Fetch(ctx context.Context) (Token, error) {
+	var t Token
+	err := a.tryRegions(func(region *awsSecretsManagerAdapter) (err error) {
+		t, err = region.Fetch(ctx)
+		return err
+	})
+	return t, err
+}
+
+// Changed reports whether the current version of the secret differs from the version last fetched, as seen by the
+// last region to succeed
+func (a *awsSecretsManagerRegionsAdapter) Changed(ctx context.Context) (bool, error) {
+	var changed bool
+	err := a.tryRegions(func(region *awsSecretsManagerAdapter) (err error) {
+		changed, err = region.Changed(ctx)
+		return err
+	})
+	return changed, err
+}
+
+// tryRegions calls fn with each region in turn, starting from the last region to succeed, until a call succeeds or
+// fails with an error other than ErrRegionUnavailable
+func (a *awsSecretsManagerRegionsAdapter) tryRegions(fn func(region *awsSecretsManagerAdapter) error) error {
+	if len(a.regions) == 0 {
+		return fmt.Errorf("%w: no regions configured", ErrRegionUnavailable)
+	}
+
+	a.mu.Lock()
+	start := a.current
+	a.mu.Unlock()
+
+	errs := make([]error, 0, len(a.regions))
+	for n := range len(a.regions) {
+		i := (start + n) % len(a.regions)
+		err := fn(a.regions[i])
+		if err == nil {
+			a.mu.Lock()
+			a.current = i
+			a.mu.Unlock()
+			return nil
+		}
+		if !errors.Is(err, ErrRegionUnavailable) {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// NewAWSSecretsManagerSplitFetcher returns a new Fetcher reading the access token and the refresh token from separate
+// secrets, e.g. to restrict access to the refresh token. The secrets are read concurrently and merged into a single
+// token: the refresh token secret is parsed like any other and only its refresh token is used. A fetch fails if either
+// secret can't be read.
+func NewAWSSecretsManagerSplitFetcher(smClient *secretsmanager.Client, accessKey string, refreshKey string, opts ...Option) *Fetcher {
+	c := newConfig(opts...)
+	return newFetcher(awsSecretsManagerSplitAdapter{
+		access: &awsSecretsManagerAdapter{
+			client:         smClient,
+			key:            accessKey,
+			decoder:        c.decoder,
+			rotationExpiry: c.rotationExpiry,
+			clockDrift:     c.clockDrift,
+			smOptFns:       c.smOptFns,
+		},
+		refresh: &awsSecretsManagerAdapter{
+			client:     smClient,
+			key:        refreshKey,
+			decoder:    c.decoder,
+			clockDrift: c.clockDrift,
+			smOptFns:   c.smOptFns,
+		},
+	},
+		c,
+	)
+}
+
+// awsSecretsManagerSplitAdapter merges the access token and refresh token read from separate secrets
+type awsSecretsManagerSplitAdapter struct {
+	access  *awsSecretsManagerAdapter
+	refresh *awsSecretsManagerAdapter
+}
+
+func (a awsSecretsManagerSplitAdapter) Fetch(ctx context.Context) (Token, error) {
+	var refresh Token
+	var refreshErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		refresh, refreshErr = a.refresh.Fetch(ctx)
+	}()
+	t, err := a.access.Fetch(ctx)
+	wg.Wait()
+
+	if err := errors.Join(err, refreshErr); err != nil {
+		return Token{}, err
+	}
+	t.RefreshToken = refresh.RefreshToken
+	return t, nil
+}
+
+// Changed reports whether the current version of either secret differs from the version last fetched
+func (a awsSecretsManagerSplitAdapter) Changed(ctx context.Context) (bool, error) {
+	changed, err := a.access.Changed(ctx)
+	if err != nil || changed {
+		return changed, err
+	}
+	return a.refresh.Changed(ctx)
+}
+
+// NewAWSSecretsManagerMultiFetcher returns a new MultiFetcher with the awsSecretsManagerMultiAdapter, for a secret
+// holding several tokens keyed by name
+func NewAWSSecretsManagerMultiFetcher(smClient *secretsmanager.Client, smKey string, opts ...Option) *MultiFetcher {
+	c := newConfig(opts...)
+	return newMultiFetcher(awsSecretsManagerMultiAdapter{
+		secret: &awsSecretsManagerAdapter{
+			client:     smClient,
+			key:        smKey,
+			decoder:    c.decoder,
+			clockDrift: c.clockDrift,
+			smOptFns:   c.smOptFns,
+		},
+	},
+		c,
+	)
+}
+
+type awsSecretsManagerMultiAdapter struct {
+	secret *awsSecretsManagerAdapter
+}
+
+func (a awsSecretsManagerMultiAdapter) FetchAll(ctx context.Context) (map[string]Token, error) {
+	key, err := a.secret.secretKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw, versionID, err := a.secret.secretValue(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := a.secret.decoder.decodeNamed(raw)
+	if err != nil {
+		return nil, sourceError("parse tokens", secretsManagerSource(key), err)
+	}
+
+	a.secret.setVersionID(versionID)
+	return tokens, nil
+}
@@ -0,0 +1,986 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockAWSSecretsManagerClient struct {
+	mock.Mock
+}
+
+func (m *mockAWSSecretsManagerClient) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	return args.Get(0).(*secretsmanager.GetSecretValueOutput), args.Error(1)
+}
+
+func (m *mockAWSSecretsManagerClient) DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	return args.Get(0).(*secretsmanager.DescribeSecretOutput), args.Error(1)
+}
+
+func TestNewAWSSecretsManagerFetcher(t *testing.T) {
+	client := &secretsmanager.Client{}
+
+	t.Run("NewAWSSecretsManagerFetcher returns fetcher with aws secrets manager adapter configured with options", func(t *testing.T) {
+		var gotRaw []byte
+		got := NewAWSSecretsManagerFetcher(client, "secret-key", WithTokenExpiryBuffer(time.Hour), WithRawResponseSink(func(raw []byte) { gotRaw = raw }), WithSecretsManagerRotationExpiry())
+		assert.Equalf(t, time.Hour, got.config.tokenExpiryBuffer, "NewAWSSecretsManagerFetcher()")
+
+		a, ok := got.adapter.(*awsSecretsManagerAdapter)
+		if !assert.Truef(t, ok, "NewAWSSecretsManagerFetcher() adapter type") {
+			return
+		}
+		assert.Samef(t, client, a.client, "NewAWSSecretsManagerFetcher() client")
+		assert.Equalf(t, "secret-key", a.key, "NewAWSSecretsManagerFetcher() key")
+		assert.Truef(t, a.rotationExpiry, "NewAWSSecretsManagerFetcher() rotation expiry")
+
+		_, _ = a.decoder.decode([]byte(`{}`))
+		assert.Equalf(t, []byte(`{}`), gotRaw, "NewAWSSecretsManagerFetcher() decoder")
+	})
+}
+
+func TestNewAWSSecretsManagerKeyResolverFetcher(t *testing.T) {
+	client := &secretsmanager.Client{}
+
+	t.Run("NewAWSSecretsManagerKeyResolverFetcher returns fetcher with aws secrets manager adapter configured with key resolver", func(t *testing.T) {
+		got := NewAWSSecretsManagerKeyResolverFetcher(client, func(ctx context.Context) (string, error) {
+			return "secret-key", nil
+		}, WithTokenExpiryBuffer(time.Hour))
+		assert.Equalf(t, time.Hour, got.config.tokenExpiryBuffer, "NewAWSSecretsManagerKeyResolverFetcher()")
+
+		a, ok := got.adapter.(*awsSecretsManagerAdapter)
+		if !assert.Truef(t, ok, "NewAWSSecretsManagerKeyResolverFetcher() adapter type") {
+			return
+		}
+		assert.Samef(t, client, a.client, "NewAWSSecretsManagerKeyResolverFetcher() client")
+		key, err := a.secretKey(context.Background())
+		assert.NoErrorf(t, err, "NewAWSSecretsManagerKeyResolverFetcher() key")
+		assert.Equalf(t, "secret-key", key, "NewAWSSecretsManagerKeyResolverFetcher() key")
+	})
+}
+
+type mockAssumeRoleClient struct {
+	mock.Mock
+}
+
+func (m *mockAssumeRoleClient) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	return args.Get(0).(*sts.AssumeRoleOutput), args.Error(1)
+}
+
+func TestNewAWSSecretsManagerAssumeRoleFetcher(t *testing.T) {
+	roleARN := "arn:aws:iam::123456789012:role/token-reader"
+
+	type mockOpts struct {
+		sts func(m *mockAssumeRoleClient)
+	}
+	tests := []struct {
+		name              string
+		mockOpts          mockOpts
+		want              Token
+		wantAuthorization string
+		wantErr           assert.ErrorAssertionFunc
+	}{
+		{
+			name: "role assumed, returns token read with assumed role credentials",
+			mockOpts: mockOpts{func(m *mockAssumeRoleClient) {
+				m.On("AssumeRole", mock.Anything, mock.MatchedBy(func(in *sts.AssumeRoleInput) bool {
+					return aws.ToString(in.RoleArn) == roleARN
+				}), mock.Anything).Return(&sts.AssumeRoleOutput{
+					Credentials: &ststypes.Credentials{
+						AccessKeyId:     aws.String("ASSUMED-KEY"),
+						SecretAccessKey: aws.String("assumed-secret"),
+						SessionToken:    aws.String("assumed-session"),
+						Expiration:      aws.Time(time.Now().Add(time.Hour)),
+					},
+				}, nil).Once()
+			}},
+			want:              Token{AccessToken: "token-123"},
+			wantAuthorization: "Credential=ASSUMED-KEY/",
+			wantErr:           assert.NoError,
+		},
+		{
+			name: "role can't be assumed, returns assume role error",
+			mockOpts: mockOpts{func(m *mockAssumeRoleClient) {
+				m.On("AssumeRole", mock.Anything, mock.Anything, mock.Anything).Return((*sts.AssumeRoleOutput)(nil), errors.New("access denied")).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrAssumeRole, i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuthorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuthorization = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(`{"SecretString":"{\"access_token\":\"token-123\"}"}`))
+			}))
+			defer server.Close()
+
+			mSTS := new(mockAssumeRoleClient)
+			tt.mockOpts.sts(mSTS)
+
+			cfg := aws.Config{
+				Region:       "eu-west-1",
+				BaseEndpoint: aws.String(server.URL),
+				Retryer:      func() aws.Retryer { return aws.NopRetryer{} },
+			}
+			got, err := NewAWSSecretsManagerAssumeRoleFetcher(cfg, mSTS, roleARN, "secret-key").Fetch(context.Background())
+			mSTS.AssertExpectations(t)
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+			assert.Truef(t, strings.Contains(gotAuthorization, tt.wantAuthorization), "Fetch() authorization %s", gotAuthorization)
+		})
+	}
+}
+
+func TestWithSecretsManagerOptions(t *testing.T) {
+	t.Run("options set, adapter passes option functions to each call", func(t *testing.T) {
+		setRegion := func(o *secretsmanager.Options) { o.Region = "eu-west-1" }
+		setAppID := func(o *secretsmanager.Options) { o.AppID = "app-123" }
+		applied := func(optFns []func(*secretsmanager.Options)) bool {
+			var o secretsmanager.Options
+			for _, fn := range optFns {
+				fn(&o)
+			}
+			return o.Region == "eu-west-1" && o.AppID == "app-123"
+		}
+
+		m := new(mockAWSSecretsManagerClient)
+		m.On("GetSecretValue", mock.Anything, mock.Anything, mock.MatchedBy(applied)).Return(&secretsmanager.GetSecretValueOutput{
+			SecretString: aws.String(`{"access_token":"token-123"}`),
+			VersionId:    aws.String("version-1"),
+		}, nil).Once()
+		m.On("DescribeSecret", mock.Anything, mock.Anything, mock.MatchedBy(applied)).Return(&secretsmanager.DescribeSecretOutput{}, nil).Once()
+
+		f := NewAWSSecretsManagerFetcher(&secretsmanager.Client{}, "secret-key", WithSecretsManagerOptions(setRegion), WithSecretsManagerOptions(setAppID))
+		a := f.adapter.(*awsSecretsManagerAdapter)
+		a.client = m
+
+		_, err := a.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		_, err = a.Changed(context.Background())
+		assert.NoErrorf(t, err, "Changed()")
+		m.AssertExpectations(t)
+	})
+}
+
+func Test_clockDriftCheck_report(t *testing.T) {
+	tests := []struct {
+		name      string
+		drift     time.Duration
+		wantCalls []time.Duration
+	}{
+		{name: "drift within max drift, doesn't call callback", drift: 5 * time.Second},
+		{name: "negative drift within max drift, doesn't call callback", drift: -5 * time.Second},
+		{name: "drift beyond max drift, calls callback", drift: 6 * time.Second, wantCalls: []time.Duration{6 * time.Second}},
+		{name: "negative drift beyond max drift, calls callback", drift: -6 * time.Second, wantCalls: []time.Duration{-6 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCalls []time.Duration
+			c := clockDriftCheck{maxDrift: 5 * time.Second, fn: func(drift time.Duration) { gotCalls = append(gotCalls, drift) }}
+			c.report(tt.drift)
+			assert.Equalf(t, tt.wantCalls, gotCalls, "report(%v)", tt.drift)
+		})
+	}
+}
+
+func TestWithMaxClockDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		_, _ = w.Write([]byte(`{"SecretString":"{\"access_token\":\"token-123\"}"}`))
+	}))
+	defer server.Close()
+
+	client := secretsmanager.NewFromConfig(aws.Config{
+		Region:       "eu-west-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  aws.AnonymousCredentials{},
+		Retryer:      func() aws.Retryer { return aws.NopRetryer{} },
+	})
+	var gotDrift time.Duration
+	f := NewAWSSecretsManagerFetcher(client, "secret-key", WithMaxClockDrift(time.Minute, func(drift time.Duration) { gotDrift = drift }))
+
+	_, err := f.Fetch(context.Background())
+	assert.NoErrorf(t, err, "Fetch()")
+	assert.InDeltaf(t, time.Hour, gotDrift, float64(5*time.Second), "Fetch() drift")
+}
+
+func Test_awsSecretsManagerAdapter_Fetch(t *testing.T) {
+	lastRotated := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	nextRotation := time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	type fields struct {
+		key               string
+		resolveKey        KeyResolver
+		rotationExpiry    bool
+		rotation          rotationSchedule
+		rotationVersionID string
+	}
+	type args struct {
+		ctx context.Context
+	}
+	type mockOpts struct {
+		client func(m *mockAWSSecretsManagerClient)
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		args          args
+		mockOpts      mockOpts
+		want          Token
+		wantVersionID string
+		wantRotation  rotationSchedule
+		wantErr       assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "secrets manager returns valid secret, returns token",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.MatchedBy(func(in *secretsmanager.GetSecretValueInput) bool {
+					return *in.SecretId == "secret-key"
+				}), mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123","token_type":"bearer","refresh_token":"refresh-123","expiry":"2030-01-02T00:00:00Z","created_at":"2025-01-02T00:00:00Z"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+			}},
+			want: Token{
+				AccessToken:  "token-123",
+				TokenType:    "bearer",
+				RefreshToken: "refresh-123",
+				Expiry:       time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+				CreatedAt:    time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+			wantVersionID: "version-1",
+			wantErr:       assert.NoError,
+		},
+		{
+			name: "key resolver returns key, fetches secret with resolved key, returns token",
+			fields: fields{resolveKey: func(ctx context.Context) (string, error) {
+				return "resolved-key", nil
+			}},
+			args: args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.MatchedBy(func(in *secretsmanager.GetSecretValueInput) bool {
+					return *in.SecretId == "resolved-key"
+				}), mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123"},
+			wantVersionID: "version-1",
+			wantErr:       assert.NoError,
+		},
+		{
+			name: "key resolver returns error, returns error",
+			fields: fields{resolveKey: func(ctx context.Context) (string, error) {
+				return "", errors.New("error")
+			}},
+			args:    args{ctx: context.Background()},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "secrets manager returns valid binary secret, returns token",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretBinary: []byte(`{"access_token":"token-123","token_type":"bearer","expiry":"2030-01-02T00:00:00Z"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+			}},
+			want: Token{
+				AccessToken: "token-123",
+				TokenType:   "bearer",
+				Expiry:      time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+			wantVersionID: "version-1",
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "secrets manager returns invalid binary secret, returns error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretBinary: []byte(`{invalid-json]`),
+				}, nil).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "secrets manager returns invalid secret, returns error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{invalid-json]`),
+				}, nil).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "secrets manager returns neither string nor binary secret, returns empty secret error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					VersionId: aws.String("version-1"),
+				}, nil).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrEmptySecret, i...) && assert.ErrorContains(t, err, "secretsmanager[secret-key]", i...)
+			},
+		},
+		{
+			name:   "secrets manager returns empty string secret, returns empty secret error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(""),
+				}, nil).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrEmptySecret, i...)
+			},
+		},
+		{
+			name:   "secrets manager returns error, returns error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, errors.New("secrets manager error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "secrets manager returns resource not found error, returns secret not found error with key",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, &smtypes.ResourceNotFoundException{Message: aws.String("not found")}).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrSecretNotFound, i...) && assert.ErrorContains(t, err, "secretsmanager[secret-key]", i...)
+			},
+		},
+		{
+			name:   "secrets manager returns access denied error, returns access denied error with key",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "denied"}).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrAccessDenied, i...) && assert.ErrorContains(t, err, "secretsmanager[secret-key]", i...)
+			},
+		},
+		{
+			name:   "secrets manager request fails to send, returns region unavailable error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, &smithyhttp.RequestSendError{Err: errors.New("dial tcp: connection refused")}).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrRegionUnavailable, i...)
+			},
+		},
+		{
+			name:   "secrets manager returns server error, returns region unavailable error",
+			fields: fields{key: "secret-key"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}},
+					Err:      errors.New("service unavailable"),
+				}).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrRegionUnavailable, i...)
+			},
+		},
+		{
+			name:   "rotation expiry set, secret without expiry, returns token with expiry from next rotation",
+			fields: fields{key: "secret-key", rotationExpiry: true},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+				m.On("DescribeSecret", mock.Anything, mock.MatchedBy(func(in *secretsmanager.DescribeSecretInput) bool {
+					return *in.SecretId == "secret-key"
+				}), mock.Anything).Return(&secretsmanager.DescribeSecretOutput{
+					LastRotatedDate:  aws.Time(lastRotated),
+					NextRotationDate: aws.Time(nextRotation),
+				}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", Expiry: nextRotation, CreatedAt: lastRotated},
+			wantVersionID: "version-1",
+			wantRotation:  rotationSchedule{lastRotated: lastRotated, nextRotation: nextRotation},
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "rotation expiry set, secret without expiry, no next rotation, returns token with expiry from rotation rules",
+			fields: fields{key: "secret-key", rotationExpiry: true},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+				m.On("DescribeSecret", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.DescribeSecretOutput{
+					LastRotatedDate: aws.Time(lastRotated),
+					RotationRules:   &smtypes.RotationRulesType{AutomaticallyAfterDays: aws.Int64(30)},
+				}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", Expiry: nextRotation, CreatedAt: lastRotated},
+			wantVersionID: "version-1",
+			wantRotation:  rotationSchedule{lastRotated: lastRotated, nextRotation: nextRotation},
+			wantErr:       assert.NoError,
+		},
+		{
+			name: "rotation expiry set, rotation schedule cached for version, returns token with expiry without describing secret",
+			fields: fields{
+				key:               "secret-key",
+				rotationExpiry:    true,
+				rotation:          rotationSchedule{lastRotated: lastRotated, nextRotation: nextRotation},
+				rotationVersionID: "version-1",
+			},
+			args: args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", Expiry: nextRotation, CreatedAt: lastRotated},
+			wantVersionID: "version-1",
+			wantRotation:  rotationSchedule{lastRotated: lastRotated, nextRotation: nextRotation},
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "rotation expiry set, secret with expiry and created date, returns token without describing secret",
+			fields: fields{key: "secret-key", rotationExpiry: true},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123","expiry":"2030-01-02T00:00:00Z","created_at":"2025-01-02T00:00:00Z"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", Expiry: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), CreatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			wantVersionID: "version-1",
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "rotation expiry set, describe secret returns error, returns error",
+			fields: fields{key: "secret-key", rotationExpiry: true},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123"}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+				m.On("DescribeSecret", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.DescribeSecretOutput{}, errors.New("error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mClient := new(mockAWSSecretsManagerClient)
+			if tt.mockOpts.client != nil {
+				tt.mockOpts.client(mClient)
+			}
+
+			a := &awsSecretsManagerAdapter{
+				client:            mClient,
+				key:               tt.fields.key,
+				resolveKey:        tt.fields.resolveKey,
+				rotationExpiry:    tt.fields.rotationExpiry,
+				rotation:          tt.fields.rotation,
+				rotationVersionID: tt.fields.rotationVersionID,
+			}
+			got, err := a.Fetch(tt.args.ctx)
+			mClient.AssertExpectations(t)
+			if !tt.wantErr(t, err, fmt.Sprintf("Fetch(%v)", tt.args.ctx)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantVersionID, a.versionID, "Fetch(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantRotation, a.rotation, "Fetch(%v)", tt.args.ctx)
+		})
+	}
+}
+
+func Test_awsSecretsManagerAdapter_Changed(t *testing.T) {
+	type fields struct {
+		key       string
+		versionID string
+	}
+	type args struct {
+		ctx context.Context
+	}
+	type mockOpts struct {
+		client func(m *mockAWSSecretsManagerClient)
+	}
+	tests := []struct {
+		name     string
+		fields   fields
+		args     args
+		mockOpts mockOpts
+		want     bool
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "current version matches fetched version, returns false",
+			fields: fields{key: "secret-key", versionID: "version-1"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, mock.MatchedBy(func(in *secretsmanager.DescribeSecretInput) bool {
+					return *in.SecretId == "secret-key"
+				}), mock.Anything).Return(&secretsmanager.DescribeSecretOutput{
+					VersionIdsToStages: map[string][]string{
+						"version-0": {"AWSPREVIOUS"},
+						"version-1": {"AWSCURRENT"},
+					},
+				}, nil).Once()
+			}},
+			want:    false,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "current version differs from fetched version, returns true",
+			fields: fields{key: "secret-key", versionID: "version-1"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.DescribeSecretOutput{
+					VersionIdsToStages: map[string][]string{
+						"version-1": {"AWSPREVIOUS"},
+						"version-2": {"AWSCURRENT", "AWSPENDING"},
+					},
+				}, nil).Once()
+			}},
+			want:    true,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "no current version, returns false",
+			fields: fields{key: "secret-key", versionID: "version-1"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.DescribeSecretOutput{}, nil).Once()
+			}},
+			want:    false,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "secrets manager returns error, returns error",
+			fields: fields{key: "secret-key", versionID: "version-1"},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.DescribeSecretOutput{}, errors.New("secrets manager error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mClient := new(mockAWSSecretsManagerClient)
+			if tt.mockOpts.client != nil {
+				tt.mockOpts.client(mClient)
+			}
+
+			a := &awsSecretsManagerAdapter{
+				client:    mClient,
+				key:       tt.fields.key,
+				versionID: tt.fields.versionID,
+			}
+			got, err := a.Changed(tt.args.ctx)
+			if !tt.wantErr(t, err, fmt.Sprintf("Changed(%v)", tt.args.ctx)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Changed(%v)", tt.args.ctx)
+		})
+	}
+}
+
+func TestNewAWSSecretsManagerMultiRegionFetcher(t *testing.T) {
+	primary, secondary := &secretsmanager.Client{}, &secretsmanager.Client{}
+
+	t.Run("NewAWSSecretsManagerMultiRegionFetcher returns fetcher with an adapter for each region in order", func(t *testing.T) {
+		got := NewAWSSecretsManagerMultiRegionFetcher([]*secretsmanager.Client{primary, secondary}, "secret-key", WithSecretsManagerRotationExpiry())
+
+		a, ok := got.adapter.(*awsSecretsManagerRegionsAdapter)
+		if !assert.Truef(t, ok, "NewAWSSecretsManagerMultiRegionFetcher() adapter type") {
+			return
+		}
+		if !assert.Lenf(t, a.regions, 2, "NewAWSSecretsManagerMultiRegionFetcher() regions") {
+			return
+		}
+		assert.Samef(t, primary, a.regions[0].client, "NewAWSSecretsManagerMultiRegionFetcher() primary client")
+		assert.Samef(t, secondary, a.regions[1].client, "NewAWSSecretsManagerMultiRegionFetcher() secondary client")
+		for _, region := range a.regions {
+			assert.Equalf(t, "secret-key", region.key, "NewAWSSecretsManagerMultiRegionFetcher() key")
+			assert.Truef(t, region.rotationExpiry, "NewAWSSecretsManagerMultiRegionFetcher() rotation expiry")
+		}
+	})
+}
+
+func Test_awsSecretsManagerRegionsAdapter_Fetch(t *testing.T) {
+	regionErr := &smithyhttp.RequestSendError{Err: errors.New("dial tcp: connection refused")}
+	secretOutput := func(accessToken string) *secretsmanager.GetSecretValueOutput {
+		return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"access_token":"` + accessToken + `"}`)}
+	}
+
+	type mockOpts struct {
+		primary   func(m *mockAWSSecretsManagerClient)
+		secondary func(m *mockAWSSecretsManagerClient)
+	}
+	tests := []struct {
+		name        string
+		current     int
+		mockOpts    mockOpts
+		want        Token
+		wantCurrent int
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name: "primary region returns token, returns token",
+			mockOpts: mockOpts{
+				primary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(secretOutput("token-primary"), nil).Once()
+				},
+			},
+			want:        Token{AccessToken: "token-primary"},
+			wantCurrent: 0,
+			wantErr:     assert.NoError,
+		},
+		{
+			name: "primary region unavailable, secondary region returns token, returns token and remembers secondary",
+			mockOpts: mockOpts{
+				primary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, regionErr).Once()
+				},
+				secondary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(secretOutput("token-secondary"), nil).Once()
+				},
+			},
+			want:        Token{AccessToken: "token-secondary"},
+			wantCurrent: 1,
+			wantErr:     assert.NoError,
+		},
+		{
+			name:    "secondary region last to succeed, returns token from secondary without probing primary",
+			current: 1,
+			mockOpts: mockOpts{
+				secondary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(secretOutput("token-secondary"), nil).Once()
+				},
+			},
+			want:        Token{AccessToken: "token-secondary"},
+			wantCurrent: 1,
+			wantErr:     assert.NoError,
+		},
+		{
+			name:    "secondary region last to succeed and now unavailable, primary returns token, returns token and remembers primary",
+			current: 1,
+			mockOpts: mockOpts{
+				primary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(secretOutput("token-primary"), nil).Once()
+				},
+				secondary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, regionErr).Once()
+				},
+			},
+			want:        Token{AccessToken: "token-primary"},
+			wantCurrent: 0,
+			wantErr:     assert.NoError,
+		},
+		{
+			name: "primary region returns secret not found, returns error without failing over",
+			mockOpts: mockOpts{
+				primary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, &smtypes.ResourceNotFoundException{Message: aws.String("not found")}).Once()
+				},
+			},
+			wantCurrent: 0,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrSecretNotFound, i...)
+			},
+		},
+		{
+			name: "all regions unavailable, returns region unavailable error",
+			mockOpts: mockOpts{
+				primary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, regionErr).Once()
+				},
+				secondary: func(m *mockAWSSecretsManagerClient) {
+					m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, regionErr).Once()
+				},
+			},
+			wantCurrent: 0,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrRegionUnavailable, i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mPrimary, mSecondary := new(mockAWSSecretsManagerClient), new(mockAWSSecretsManagerClient)
+			if tt.mockOpts.primary != nil {
+				tt.mockOpts.primary(mPrimary)
+			}
+			if tt.mockOpts.secondary != nil {
+				tt.mockOpts.secondary(mSecondary)
+			}
+
+			a := &awsSecretsManagerRegionsAdapter{
+				regions: []*awsSecretsManagerAdapter{
+					{client: mPrimary, key: "secret-key"},
+					{client: mSecondary, key: "secret-key"},
+				},
+				current: tt.current,
+			}
+			got, err := a.Fetch(context.Background())
+			mPrimary.AssertExpectations(t)
+			mSecondary.AssertExpectations(t)
+			assert.Equalf(t, tt.wantCurrent, a.current, "Fetch()")
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
+
+func TestNewAWSSecretsManagerSplitFetcher(t *testing.T) {
+	client := &secretsmanager.Client{}
+
+	t.Run("NewAWSSecretsManagerSplitFetcher returns fetcher with an adapter for each secret", func(t *testing.T) {
+		got := NewAWSSecretsManagerSplitFetcher(client, "access-key", "refresh-key", WithSecretsManagerRotationExpiry())
+
+		a, ok := got.adapter.(awsSecretsManagerSplitAdapter)
+		if !assert.Truef(t, ok, "NewAWSSecretsManagerSplitFetcher() adapter type") {
+			return
+		}
+		assert.Samef(t, client, a.access.client, "NewAWSSecretsManagerSplitFetcher() access client")
+		assert.Equalf(t, "access-key", a.access.key, "NewAWSSecretsManagerSplitFetcher() access key")
+		assert.Truef(t, a.access.rotationExpiry, "NewAWSSecretsManagerSplitFetcher() access rotation expiry")
+		assert.Samef(t, client, a.refresh.client, "NewAWSSecretsManagerSplitFetcher() refresh client")
+		assert.Equalf(t, "refresh-key", a.refresh.key, "NewAWSSecretsManagerSplitFetcher() refresh key")
+		assert.Falsef(t, a.refresh.rotationExpiry, "NewAWSSecretsManagerSplitFetcher() refresh rotation expiry")
+	})
+}
+
+func Test_awsSecretsManagerSplitAdapter_Fetch(t *testing.T) {
+	secretID := func(key string) any {
+		return mock.MatchedBy(func(in *secretsmanager.GetSecretValueInput) bool { return aws.ToString(in.SecretId) == key })
+	}
+	accessOutput := &secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"access_token":"token-123","expiry":1893542400}`)}
+	refreshOutput := &secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"refresh_token":"refresh-123"}`)}
+
+	tests := []struct {
+		name     string
+		mockOpts func(m *mockAWSSecretsManagerClient)
+		want     Token
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name: "both secrets returned, returns merged token",
+			mockOpts: func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, secretID("access-key"), mock.Anything).Return(accessOutput, nil).Once()
+				m.On("GetSecretValue", mock.Anything, secretID("refresh-key"), mock.Anything).Return(refreshOutput, nil).Once()
+			},
+			want:    Token{AccessToken: "token-123", RefreshToken: "refresh-123", Expiry: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "access token secret returns error, returns error",
+			mockOpts: func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, secretID("access-key"), mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, errors.New("error")).Once()
+				m.On("GetSecretValue", mock.Anything, secretID("refresh-key"), mock.Anything).Return(refreshOutput, nil).Once()
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "secretsmanager[access-key]", i...)
+			},
+		},
+		{
+			name: "refresh token secret returns error, returns error",
+			mockOpts: func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, secretID("access-key"), mock.Anything).Return(accessOutput, nil).Once()
+				m.On("GetSecretValue", mock.Anything, secretID("refresh-key"), mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, &smtypes.ResourceNotFoundException{Message: aws.String("not found")}).Once()
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrSecretNotFound, i...) && assert.ErrorContains(t, err, "secretsmanager[refresh-key]", i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(mockAWSSecretsManagerClient)
+			tt.mockOpts(m)
+
+			a := awsSecretsManagerSplitAdapter{
+				access:  &awsSecretsManagerAdapter{client: m, key: "access-key"},
+				refresh: &awsSecretsManagerAdapter{client: m, key: "refresh-key"},
+			}
+			got, err := a.Fetch(context.Background())
+			m.AssertExpectations(t)
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
+
+func Test_awsSecretsManagerSplitAdapter_Changed(t *testing.T) {
+	describeOutput := func(currentVersionID string) *secretsmanager.DescribeSecretOutput {
+		return &secretsmanager.DescribeSecretOutput{VersionIdsToStages: map[string][]string{currentVersionID: {"AWSCURRENT"}}}
+	}
+	secretID := func(key string) any {
+		return mock.MatchedBy(func(in *secretsmanager.DescribeSecretInput) bool { return aws.ToString(in.SecretId) == key })
+	}
+
+	tests := []struct {
+		name     string
+		mockOpts func(m *mockAWSSecretsManagerClient)
+		want     bool
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name: "neither secret changed, returns false",
+			mockOpts: func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, secretID("access-key"), mock.Anything).Return(describeOutput("access-1"), nil).Once()
+				m.On("DescribeSecret", mock.Anything, secretID("refresh-key"), mock.Anything).Return(describeOutput("refresh-1"), nil).Once()
+			},
+			want:    false,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "access token secret changed, returns true without describing refresh token secret",
+			mockOpts: func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, secretID("access-key"), mock.Anything).Return(describeOutput("access-2"), nil).Once()
+			},
+			want:    true,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "refresh token secret changed, returns true",
+			mockOpts: func(m *mockAWSSecretsManagerClient) {
+				m.On("DescribeSecret", mock.Anything, secretID("access-key"), mock.Anything).Return(describeOutput("access-1"), nil).Once()
+				m.On("DescribeSecret", mock.Anything, secretID("refresh-key"), mock.Anything).Return(describeOutput("refresh-2"), nil).Once()
+			},
+			want:    true,
+			wantErr: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(mockAWSSecretsManagerClient)
+			tt.mockOpts(m)
+
+			a := awsSecretsManagerSplitAdapter{
+				access:  &awsSecretsManagerAdapter{client: m, key: "access-key", versionID: "access-1"},
+				refresh: &awsSecretsManagerAdapter{client: m, key: "refresh-key", versionID: "refresh-1"},
+			}
+			got, err := a.Changed(context.Background())
+			m.AssertExpectations(t)
+			if !tt.wantErr(t, err, "Changed()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Changed()")
+		})
+	}
+}
+
+func Test_awsSecretsManagerMultiAdapter_FetchAll(t *testing.T) {
+	type mockOpts struct {
+		client func(m *mockAWSSecretsManagerClient)
+	}
+	tests := []struct {
+		name          string
+		mockOpts      mockOpts
+		want          map[string]Token
+		wantVersionID string
+		wantErr       assert.ErrorAssertionFunc
+	}{
+		{
+			name: "secrets manager returns valid secret, returns tokens",
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.MatchedBy(func(in *secretsmanager.GetSecretValueInput) bool {
+					return *in.SecretId == "secret-key"
+				}), mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"a":{"access_token":"token-a"},"b":{"access_token":"token-b"}}`),
+					VersionId:    aws.String("version-1"),
+				}, nil).Once()
+			}},
+			want: map[string]Token{
+				"a": {AccessToken: "token-a"},
+				"b": {AccessToken: "token-b"},
+			},
+			wantVersionID: "version-1",
+			wantErr:       assert.NoError,
+		},
+		{
+			name: "secrets manager returns invalid secret, returns error",
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"access_token":"token-123"}`),
+				}, nil).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name: "secrets manager returns error, returns error",
+			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
+				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, errors.New("secrets manager error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mClient := new(mockAWSSecretsManagerClient)
+			if tt.mockOpts.client != nil {
+				tt.mockOpts.client(mClient)
+			}
+
+			a := awsSecretsManagerMultiAdapter{
+				secret: &awsSecretsManagerAdapter{client: mClient, key: "secret-key"},
+			}
+			got, err := a.FetchAll(context.Background())
+			if !tt.wantErr(t, err, "FetchAll()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "FetchAll()")
+			assert.Equalf(t, tt.wantVersionID, a.secret.versionID, "FetchAll()")
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package token
+
+import (
+	"math"
+	"time"
+)
+
+// BackoffStrategy returns the delay before each retry of a failed adapter call, e.g. to use decorrelated jitter or a
+// schedule tuned to the secret backend. attempt is 1 for the first retry.
+type BackoffStrategy interface {
+	NextDelay(attempt int) time.Duration
+}
+
+// WithBackoff sets the strategy delaying the retries made by WithStartupBackoff, replacing the exponential backoff from
+// its initial delay. Retries are still limited to the startup window.
+func WithBackoff(strategy BackoffStrategy) Option {
+	return func(c *config) { c.backoff = strategy }
+}
+
+// NewExponentialBackoff returns a BackoffStrategy delaying the first retry by initial, doubling after each retry up to
+// maxDelay, or without limit if maxDelay is zero
+func NewExponentialBackoff(initial, maxDelay time.Duration) BackoffStrategy {
+	return exponentialBackoff{initial: initial, maxDelay: maxDelay}
+}
+
+type exponentialBackoff struct {
+	initial  time.Duration
+	maxDelay time.Duration
+}
+
+func (b exponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.initial
+	for range attempt - 1 {
+		if delay > math.MaxInt64/2 {
+			delay = math.MaxInt64
+			break
+		}
+		delay *= 2
+	}
+	if b.maxDelay > 0 {
+		return min(delay, b.maxDelay)
+	}
+	return delay
+}
+
+// NewConstantBackoff returns a BackoffStrategy delaying every retry by delay
+func NewConstantBackoff(delay time.Duration) BackoffStrategy {
+	return constantBackoff{delay: delay}
+}
+
+type constantBackoff struct {
+	delay time.Duration
+}
+
+func (b constantBackoff) NextDelay(int) time.Duration {
+	return b.delay
+}
+
+// backoffStrategy returns the strategy delaying startup retries, defaulting to exponential backoff from the startup
+// delay
+func (c config) backoffStrategy() BackoffStrategy {
+	if c.backoff != nil {
+		return c.backoff
+	}
+	return NewExponentialBackoff(c.startupDelay, 0)
+}
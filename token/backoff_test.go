@@ -0,0 +1,61 @@
+package token
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_exponentialBackoff_NextDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  time.Duration
+		maxDelay time.Duration
+		attempt  int
+		want     time.Duration
+	}{
+		{name: "first attempt, returns initial delay", initial: time.Second, attempt: 1, want: time.Second},
+		{name: "third attempt, returns initial delay doubled twice", initial: time.Second, attempt: 3, want: 4 * time.Second},
+		{name: "max delay set, delay within max, returns delay", initial: time.Second, maxDelay: time.Minute, attempt: 3, want: 4 * time.Second},
+		{name: "max delay set, delay beyond max, returns max delay", initial: time.Second, maxDelay: 5 * time.Second, attempt: 10, want: 5 * time.Second},
+		{name: "delay overflows, returns max duration", initial: time.Second, attempt: 100, want: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewExponentialBackoff(tt.initial, tt.maxDelay)
+			assert.Equalf(t, tt.want, b.NextDelay(tt.attempt), "NextDelay(%d)", tt.attempt)
+		})
+	}
+}
+
+func Test_constantBackoff_NextDelay(t *testing.T) {
+	b := NewConstantBackoff(time.Second)
+	for _, attempt := range []int{1, 2, 10} {
+		assert.Equalf(t, time.Second, b.NextDelay(attempt), "NextDelay(%d)", attempt)
+	}
+}
+
+func Test_config_backoffStrategy(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config
+		want   BackoffStrategy
+	}{
+		{
+			name:   "backoff not set, returns exponential backoff from startup delay",
+			config: config{startupDelay: time.Second},
+			want:   exponentialBackoff{initial: time.Second},
+		},
+		{
+			name:   "backoff set, returns backoff",
+			config: config{startupDelay: time.Second, backoff: constantBackoff{delay: time.Minute}},
+			want:   constantBackoff{delay: time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, tt.config.backoffStrategy(), "backoffStrategy()")
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"sync"
+	"time"
+)
+
+// NewCachingAdapter returns an Adapter caching tokens fetched from the inner Adapter until buffer before they expire,
+// so caching can be composed with other decorators, e.g. caching tokens fetched through a failover adapter. Concurrent
+// fetches share a single call to the inner Adapter. The system clock is used if clk is nil.
+func NewCachingAdapter(inner Adapter, clk clock.Clock, buffer time.Duration) Adapter {
+	if clk == nil {
+		clk = clock.NewSystem()
+	}
+	return &cachingAdapter{
+		inner:  inner,
+		clock:  clk,
+		buffer: buffer,
+	}
+}
+
+type cachingAdapter struct {
+	inner  Adapter
+	clock  clock.Clock
+	buffer time.Duration
+
+	mu    sync.Mutex
+	token Token
+}
+
+func (a *cachingAdapter) Fetch(ctx context.Context) (Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token.Valid(a.clock.Now(), a.buffer) {
+		return a.token.Clone(), nil
+	}
+
+	t, err := a.inner.Fetch(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	a.token = t
+	return t.Clone(), nil
+}
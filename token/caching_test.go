@@ -0,0 +1,101 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestNewCachingAdapter(t *testing.T) {
+	inner := new(mockAdapter)
+
+	t.Run("NewCachingAdapter returns caching adapter with system clock if nil", func(t *testing.T) {
+		got, ok := NewCachingAdapter(inner, nil, time.Minute).(*cachingAdapter)
+		if !assert.Truef(t, ok, "NewCachingAdapter() adapter type") {
+			return
+		}
+		assert.Samef(t, inner, got.inner, "NewCachingAdapter() inner")
+		assert.Equalf(t, clock.NewSystem(), got.clock, "NewCachingAdapter() clock")
+		assert.Equalf(t, time.Minute, got.buffer, "NewCachingAdapter() buffer")
+	})
+}
+
+func Test_cachingAdapter_Fetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	cached := Token{AccessToken: "cached-token-123", Expiry: now.Add(time.Hour)}
+	fetched := Token{AccessToken: "token-123", Expiry: now.Add(2 * time.Hour)}
+
+	type mockOpts struct {
+		inner func(m *mockAdapter)
+	}
+	tests := []struct {
+		name      string
+		token     Token
+		mockOpts  mockOpts
+		want      Token
+		wantToken Token
+		wantErr   assert.ErrorAssertionFunc
+	}{
+		{
+			name:      "valid token cached, returns cached token",
+			token:     cached,
+			want:      cached,
+			wantToken: cached,
+			wantErr:   assert.NoError,
+		},
+		{
+			name: "no token cached, inner adapter returns token, returns and caches token",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(fetched, nil).Once()
+			}},
+			want:      fetched,
+			wantToken: fetched,
+			wantErr:   assert.NoError,
+		},
+		{
+			name:  "token cached expiring within buffer, inner adapter returns token, returns and caches token",
+			token: Token{AccessToken: "cached-token-123", Expiry: now.Add(30 * time.Second)},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(fetched, nil).Once()
+			}},
+			want:      fetched,
+			wantToken: fetched,
+			wantErr:   assert.NoError,
+		},
+		{
+			name:  "token cached expiring within buffer, inner adapter returns error, returns error and keeps cached token",
+			token: Token{AccessToken: "cached-token-123", Expiry: now.Add(30 * time.Second)},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantToken: Token{AccessToken: "cached-token-123", Expiry: now.Add(30 * time.Second)},
+			wantErr:   assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mInner := new(mockAdapter)
+			if tt.mockOpts.inner != nil {
+				tt.mockOpts.inner(mInner)
+			}
+
+			a := &cachingAdapter{
+				inner:  mInner,
+				clock:  clock.NewFixed(now),
+				buffer: time.Minute,
+				token:  tt.token,
+			}
+			got, err := a.Fetch(context.Background())
+			mInner.AssertExpectations(t)
+			assert.Equalf(t, tt.wantToken, a.token, "Fetch() cached token")
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"log/slog"
+	"time"
+)
+
+// WithNowFunc sets the function used to read the current time, e.g. to make expiry deterministic in tests without
+// depending on a clock package. The system time is used by default.
+func WithNowFunc(now func() time.Time) Option {
+	return func(c *config) { c.now = now }
+}
+
+// WithClockJumpDetection detects the clock moving backwards by more than threshold between fetches, e.g. after an NTP
+// step or a VM resume, which could make an expired token appear valid. A jump is passed to the warning callback wrapped
+// in ErrClockMovedBackwards, and the token is refreshed.
+func WithClockJumpDetection(threshold time.Duration) Option {
+	return func(c *config) { c.clockJumpThreshold = threshold }
+}
+
+// clockMovedBackwards reports whether the clock has moved backwards by more than the threshold since the last call,
+// warning about the jump if so. The wall clock is compared, as monotonic readings never move backwards.
+func (f *Fetcher) clockMovedBackwards(ctx context.Context) bool {
+	if f.config.clockJumpThreshold <= 0 {
+		return false
+	}
+
+	now, last := f.clock.Now().Round(0), f.lastNow
+	f.lastNow = now
+	jump := last.Sub(now)
+	if last.IsZero() || jump <= f.config.clockJumpThreshold {
+		return false
+	}
+
+	f.log(ctx, slog.LevelWarn, "clock moved backwards", "jump", jump)
+	if f.config.onWarning != nil {
+		f.config.onWarning(f.named(fmt.Errorf("%w by %s", ErrClockMovedBackwards, jump)))
+	}
+	return true
+}
+
+func (c config) clock() clock.Clock {
+	if c.now == nil {
+		return clock.NewSystem()
+	}
+	return nowFuncClock(c.now)
+}
+
+// nowFuncClock is a clock.Clock reading the current time from a function
+type nowFuncClock func() time.Time
+
+func (c nowFuncClock) Now() time.Time {
+	return c()
+}
+
+func (c nowFuncClock) Since(t time.Time) time.Duration {
+	return c().Sub(t)
+}
+
+func (c nowFuncClock) Until(t time.Time) time.Duration {
+	return t.Sub(c())
+}
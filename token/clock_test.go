@@ -0,0 +1,127 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func Test_config_clock(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("now func not set, returns system clock", func(t *testing.T) {
+		assert.Equalf(t, clock.NewSystem(), config{}.clock(), "clock()")
+	})
+
+	t.Run("now func set, returns clock reading time from now func", func(t *testing.T) {
+		got := newConfig(WithNowFunc(func() time.Time { return now })).clock()
+		assert.Equalf(t, now, got.Now(), "clock().Now()")
+		assert.Equalf(t, time.Hour, got.Since(now.Add(-time.Hour)), "clock().Since()")
+		assert.Equalf(t, time.Hour, got.Until(now.Add(time.Hour)), "clock().Until()")
+	})
+}
+
+func TestWithNowFunc(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	a := new(mockAdapter)
+	a.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}, nil).Once()
+
+	f := New(a, WithNowFunc(func() time.Time { return now }))
+	_, err := f.Fetch(context.Background())
+	assert.NoErrorf(t, err, "Fetch()")
+	assert.Equalf(t, now, f.fetchedAt, "Fetch() fetchedAt")
+	a.AssertExpectations(t)
+}
+
+func TestFetcher_clockMovedBackwards(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		threshold     time.Duration
+		lastNow       time.Time
+		want          bool
+		wantOnWarning int
+	}{
+		{
+			name:    "detection disabled, returns false",
+			lastNow: now.Add(time.Hour),
+			want:    false,
+		},
+		{
+			name:      "first call, returns false",
+			threshold: time.Minute,
+			want:      false,
+		},
+		{
+			name:      "clock moved forwards, returns false",
+			threshold: time.Minute,
+			lastNow:   now.Add(-time.Hour),
+			want:      false,
+		},
+		{
+			name:      "clock moved backwards within threshold, returns false",
+			threshold: time.Minute,
+			lastNow:   now.Add(time.Minute),
+			want:      false,
+		},
+		{
+			name:          "clock moved backwards beyond threshold, returns true and calls on warning",
+			threshold:     time.Minute,
+			lastNow:       now.Add(time.Hour),
+			want:          true,
+			wantOnWarning: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOnWarning int
+			f := &Fetcher{
+				config: config{clockJumpThreshold: tt.threshold, onWarning: func(err error) {
+					assert.ErrorIsf(t, err, ErrClockMovedBackwards, "clockMovedBackwards() warning")
+					gotOnWarning++
+				}},
+				clock:   clock.NewFixed(now),
+				lastNow: tt.lastNow,
+			}
+			assert.Equalf(t, tt.want, f.clockMovedBackwards(context.Background()), "clockMovedBackwards()")
+			assert.Equalf(t, tt.wantOnWarning, gotOnWarning, "clockMovedBackwards()")
+			if tt.threshold > 0 {
+				assert.Equalf(t, now, f.lastNow, "clockMovedBackwards() last now")
+			}
+		})
+	}
+}
+
+func TestWithClockJumpDetection(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+
+	t.Run("clock moved backwards, refreshes valid cached token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Twice()
+
+		current := now
+		var gotOnWarning int
+		f := New(mAdapter,
+			WithNowFunc(func() time.Time { return current }),
+			WithClockJumpDetection(time.Minute),
+			WithAsyncRefresh(),
+			WithOnWarning(func(err error) { gotOnWarning++ }),
+		)
+
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		current = now.Add(10 * time.Minute)
+		_, err = f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		current = now
+		_, err = f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, 1, gotOnWarning, "WithClockJumpDetection()")
+		mAdapter.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,19 @@
+package token
+
+import (
+	"context"
+)
+
+type contextTokenKey struct{}
+
+// ContextWithToken returns a copy of ctx carrying t, e.g. per-request credentials injected by middleware. Fetch returns
+// a token carried by the context instead of the cached token.
+func ContextWithToken(ctx context.Context, t Token) context.Context {
+	return context.WithValue(ctx, contextTokenKey{}, t)
+}
+
+// TokenFromContext returns the token carried by ctx, and whether one was found
+func TokenFromContext(ctx context.Context) (Token, bool) {
+	t, ok := ctx.Value(contextTokenKey{}).(Token)
+	return t, ok
+}
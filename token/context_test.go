@@ -0,0 +1,36 @@
+package token
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenFromContext(t *testing.T) {
+	tok := Token{AccessToken: "token-123"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   Token
+		wantOk bool
+	}{
+		{
+			name: "no token in context, returns false",
+			ctx:  context.Background(),
+		},
+		{
+			name:   "token in context, returns token",
+			ctx:    ContextWithToken(context.Background(), tok),
+			want:   tok,
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := TokenFromContext(tt.ctx)
+			assert.Equalf(t, tt.want, got, "TokenFromContext(%v)", tt.ctx)
+			assert.Equalf(t, tt.wantOk, gotOk, "TokenFromContext(%v)", tt.ctx)
+		})
+	}
+}
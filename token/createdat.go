@@ -0,0 +1,40 @@
+package token
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithRefreshOnCreatedAtChange polls the adapter at most once per interval, replacing the cached token if the polled
+// token has a later created date, even if the cached token has not expired. It suits sources whose created date is
+// reliable but whose expiry date isn't. Each poll costs an adapter call; a failed poll leaves the cached token in place.
+func WithRefreshOnCreatedAtChange(interval time.Duration) Option {
+	return func(c *config) { c.createdAtInterval = interval }
+}
+
+// pollCreatedAt fetches a token from the adapter if the created date check interval has elapsed, caching and returning
+// it if it was created after the cached token
+func (f *Fetcher) pollCreatedAt(ctx context.Context) (Token, bool) {
+	if f.config.createdAtInterval <= 0 {
+		return Token{}, false
+	}
+
+	now := f.clock.Now()
+	if now.Before(f.createdAtCheck.Add(f.config.createdAtInterval)) {
+		return Token{}, false
+	}
+	f.createdAtCheck = now
+
+	t, err := f.config.refreshQueue.fetch(ctx, f.fetchFromAdapter)
+	if err != nil {
+		f.log(ctx, slog.LevelDebug, "token created date check failed", "err", err)
+		return Token{}, false
+	}
+	if !t.CreatedAt.After(f.token.CreatedAt) {
+		return Token{}, false
+	}
+
+	t, err = f.refreshed(ctx, now, t, nil)
+	return t, err == nil
+}
@@ -0,0 +1,119 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestFetcher_pollCreatedAt(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	cached := Token{AccessToken: "old-token-123", CreatedAt: now.Add(-time.Hour)}
+
+	type fields struct {
+		config         config
+		createdAtCheck time.Time
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name               string
+		fields             fields
+		mockOpts           mockOpts
+		want               Token
+		wantOk             bool
+		wantToken          Token
+		wantCreatedAtCheck time.Time
+	}{
+		{
+			name:      "interval not set, returns false",
+			fields:    fields{config: config{}},
+			wantToken: cached,
+		},
+		{
+			name:               "interval set, checked within interval, returns false",
+			fields:             fields{config: config{createdAtInterval: time.Minute}, createdAtCheck: now.Add(-time.Second)},
+			wantToken:          cached,
+			wantCreatedAtCheck: now.Add(-time.Second),
+		},
+		{
+			name:   "interval set, adapter returns token created later, caches token and returns true",
+			fields: fields{config: config{createdAtInterval: time.Minute}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", CreatedAt: now}, nil).Once()
+			}},
+			want:               Token{AccessToken: "token-123", CreatedAt: now},
+			wantOk:             true,
+			wantToken:          Token{AccessToken: "token-123", CreatedAt: now},
+			wantCreatedAtCheck: now,
+		},
+		{
+			name:   "interval set, adapter returns token created at same time, returns false",
+			fields: fields{config: config{createdAtInterval: time.Minute}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", CreatedAt: now.Add(-time.Hour)}, nil).Once()
+			}},
+			wantToken:          cached,
+			wantCreatedAtCheck: now,
+		},
+		{
+			name:   "interval set, adapter returns error, returns false",
+			fields: fields{config: config{createdAtInterval: time.Minute}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantToken:          cached,
+			wantCreatedAtCheck: now,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &Fetcher{
+				config:         tt.fields.config,
+				clock:          clock.NewFixed(now),
+				adapter:        mAdapter,
+				token:          cached,
+				createdAtCheck: tt.fields.createdAtCheck,
+			}
+			got, ok := f.pollCreatedAt(context.Background())
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.want, got, "pollCreatedAt()")
+			assert.Equalf(t, tt.wantOk, ok, "pollCreatedAt()")
+			assert.Equalf(t, tt.wantToken, f.token, "pollCreatedAt() token")
+			assert.Equalf(t, tt.wantCreatedAtCheck, f.createdAtCheck, "pollCreatedAt() created at check")
+		})
+	}
+}
+
+func TestFetcher_Fetch_refreshOnCreatedAtChange(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("valid token cached, adapter returns token created later, returns refreshed token", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", CreatedAt: now}, nil).Once()
+		f := New(m, WithNowFunc(clock.NewFixed(now).Now), WithRefreshOnCreatedAtChange(time.Minute),
+			WithInitialToken(Token{AccessToken: "old-token-123", CreatedAt: now.Add(-time.Hour)}))
+
+		got, meta, err := f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, Token{AccessToken: "token-123", CreatedAt: now}, got, "FetchWithMeta()")
+		assert.Truef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+
+		// The refresh restarts the interval, so the next fetch is served from the cache
+		got, meta, err = f.FetchWithMeta(context.Background())
+		assert.NoErrorf(t, err, "FetchWithMeta()")
+		assert.Equalf(t, Token{AccessToken: "token-123", CreatedAt: now}, got, "FetchWithMeta()")
+		assert.Falsef(t, meta.Refreshed, "FetchWithMeta() refreshed")
+		m.AssertExpectations(t)
+	})
+}
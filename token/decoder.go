@@ -0,0 +1,177 @@
+package token
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Unmarshaler parses JSON encoded data into the value pointed to by v, e.g. a faster drop-in replacement for
+// encoding/json such as json-iterator or Sonic
+type Unmarshaler interface {
+	Unmarshal(data []byte, v any) error
+}
+
+// secretDecoder parses the raw secret values read by the built-in adapters into tokens
+type secretDecoder struct {
+	rawSink     func(raw []byte)
+	transformer func(raw []byte) ([]byte, error)
+	unmarshaler Unmarshaler
+	strict      bool
+	path        []string
+}
+
+// WithRawResponseSink sets a function receiving a copy of the raw secret value read by the built-in adapters before it
+// is parsed, to debug unexpected tokens.
+//
+// The raw value contains secret material, including the access token. It should only be enabled while debugging and
+// the sink must not write it anywhere it could be exposed.
+func WithRawResponseSink(sink func(raw []byte)) Option {
+	return func(c *config) { c.decoder.rawSink = sink }
+}
+
+// WithStrictJSON makes the built-in adapters fail to parse secret values containing unknown fields, for callers that
+// treat schema drift as an error. Parsing is lenient by default.
+func WithStrictJSON() Option {
+	return func(c *config) { c.decoder.strict = true }
+}
+
+// WithSecretTransformer sets a function transforming the raw secret value read by the built-in adapters before it is
+// parsed, e.g. to base64 decode or decompress secrets stored encoded. The raw response sink receives the value before
+// it is transformed.
+func WithSecretTransformer(transformer func(raw []byte) ([]byte, error)) Option {
+	return func(c *config) { c.decoder.transformer = transformer }
+}
+
+// WithJSONUnmarshaler sets the Unmarshaler the built-in adapters parse secret values, JWT claims and introspection
+// responses with, defaulting to encoding/json. Strict JSON checks for unknown fields, and the expiry, created_at and
+// scope fields of tokens, are parsed with encoding/json regardless.
+func WithJSONUnmarshaler(unmarshaler Unmarshaler) Option {
+	return func(c *config) { c.decoder.unmarshaler = unmarshaler }
+}
+
+// WithSecretJSONPath sets the dotted path of the token within the JSON secret values read by the built-in adapters,
+// e.g. "credentials.api" for a token nested under the credentials and api fields. If the path leads to a string, it is
+// used as the access token. Secret values without the path fail to parse. An empty path reads the token from the top
+// level of the secret value, as by default.
+func WithSecretJSONPath(path string) Option {
+	return func(c *config) {
+		c.decoder.path = nil
+		if path != "" {
+			c.decoder.path = strings.Split(path, ".")
+		}
+	}
+}
+
+// decode parses the raw secret value into a token, wrapping errors in ErrMalformedSecret
+func (d secretDecoder) decode(raw []byte) (Token, error) {
+	t, err := d.decodeToken(raw)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %w", ErrMalformedSecret, err)
+	}
+	return t, nil
+}
+
+func (d secretDecoder) decodeToken(raw []byte) (Token, error) {
+	raw, err := d.prepare(raw)
+	if err != nil {
+		return Token{}, err
+	}
+	if d.strict {
+		if err := disallowUnknownFields(raw, &tokenFields{}); err != nil {
+			return Token{}, err
+		}
+	}
+
+	return unmarshalToken(raw, d.unmarshal)
+}
+
+// decodeNamed parses the raw secret value into named tokens, wrapping errors in ErrMalformedSecret
+func (d secretDecoder) decodeNamed(raw []byte) (map[string]Token, error) {
+	tokens, err := d.decodeTokens(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedSecret, err)
+	}
+	return tokens, nil
+}
+
+func (d secretDecoder) decodeTokens(raw []byte) (map[string]Token, error) {
+	raw, err := d.prepare(raw)
+	if err != nil {
+		return nil, err
+	}
+	if d.strict {
+		if err := disallowUnknownFields(raw, &map[string]tokenFields{}); err != nil {
+			return nil, err
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := d.unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]Token, len(fields))
+	for name, value := range fields {
+		t, err := unmarshalToken(value, d.unmarshal)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse token %s: %w", name, err)
+		}
+		tokens[name] = t
+	}
+	return tokens, nil
+}
+
+// prepare passes the raw secret value to the raw sink, then applies the transformer and extracts the value at the path
+func (d secretDecoder) prepare(raw []byte) ([]byte, error) {
+	if d.rawSink != nil {
+		d.rawSink(bytes.Clone(raw))
+	}
+	if d.transformer != nil {
+		var err error
+		if raw, err = d.transformer(raw); err != nil {
+			return nil, fmt.Errorf("unable to transform secret value: %w", err)
+		}
+	}
+	return d.extract(raw)
+}
+
+// extract returns the value at the path within the raw secret value, if set, or raw otherwise. A string value is
+// returned as a token with the string as its access token.
+func (d secretDecoder) extract(raw []byte) ([]byte, error) {
+	if len(d.path) == 0 {
+		return raw, nil
+	}
+
+	for i, name := range d.path {
+		var fields map[string]json.RawMessage
+		if err := d.unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("unable to read secret JSON path %s: %w", strings.Join(d.path, "."), err)
+		}
+		var ok bool
+		if raw, ok = fields[name]; !ok {
+			return nil, fmt.Errorf("secret JSON path %s not found", strings.Join(d.path[:i+1], "."))
+		}
+	}
+
+	var accessToken string
+	if err := json.Unmarshal(raw, &accessToken); err != nil {
+		return raw, nil
+	}
+	return json.Marshal(map[string]string{"access_token": accessToken})
+}
+
+// unmarshal parses raw with the Unmarshaler, if set, or encoding/json otherwise
+func (d secretDecoder) unmarshal(raw []byte, v any) error {
+	if d.unmarshaler == nil {
+		return json.Unmarshal(raw, v)
+	}
+	return d.unmarshaler.Unmarshal(raw, v)
+}
+
+func disallowUnknownFields(raw []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
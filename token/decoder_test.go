@@ -0,0 +1,317 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func base64Decode(raw []byte) ([]byte, error) {
+	return base64.StdEncoding.AppendDecode(nil, raw)
+}
+
+type mockUnmarshaler struct {
+	calls int
+}
+
+func (u *mockUnmarshaler) Unmarshal(data []byte, v any) error {
+	u.calls++
+	return json.Unmarshal(data, v)
+}
+
+func Test_secretDecoder_decode(t *testing.T) {
+	tests := []struct {
+		name        string
+		withRawSink bool
+		strict      bool
+		transformer func(raw []byte) ([]byte, error)
+		unmarshaler *mockUnmarshaler
+		path        string
+		wantCalls   int
+		raw         []byte
+		want        Token
+		wantRaw     []byte
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "valid secret, returns token",
+			raw:     []byte(`{"access_token":"token-123"}`),
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid secret, returns malformed secret error",
+			raw:  []byte(`{invalid-json]`),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
+		{
+			name:    "unknown field, returns token with extra field",
+			raw:     []byte(`{"access_token":"token-123","rotated_by":"tool"}`),
+			want:    Token{AccessToken: "token-123", Extra: map[string]string{"rotated_by": "tool"}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "strict, known fields, returns token",
+			strict:  true,
+			raw:     []byte(`{"access_token":"token-123","token_type":"bearer","refresh_token":"refresh-123","expiry":1893542400,"created_at":"2025-01-02T00:00:00Z"}`),
+			want:    Token{AccessToken: "token-123", TokenType: "bearer", RefreshToken: "refresh-123", Expiry: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), CreatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "strict, unknown field, returns error",
+			strict:  true,
+			raw:     []byte(`{"access_token":"token-123","rotated_by":"tool"}`),
+			wantErr: assert.Error,
+		},
+		{
+			name:        "raw sink set, valid secret, passes raw secret to sink and returns token",
+			withRawSink: true,
+			raw:         []byte(`{"access_token":"token-123"}`),
+			want:        Token{AccessToken: "token-123"},
+			wantRaw:     []byte(`{"access_token":"token-123"}`),
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "raw sink set, invalid secret, passes raw secret to sink and returns error",
+			withRawSink: true,
+			raw:         []byte(`{invalid-json]`),
+			wantRaw:     []byte(`{invalid-json]`),
+			wantErr:     assert.Error,
+		},
+		{
+			name:        "transformer set, transforms secret and returns token",
+			transformer: base64Decode,
+			raw:         []byte(base64.StdEncoding.EncodeToString([]byte(`{"access_token":"token-123"}`))),
+			want:        Token{AccessToken: "token-123"},
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "transformer returns error, returns error",
+			transformer: func(raw []byte) ([]byte, error) { return nil, errors.New("error") },
+			raw:         []byte(`{"access_token":"token-123"}`),
+			wantErr:     assert.Error,
+		},
+		{
+			name:        "raw sink and transformer set, passes untransformed secret to sink and returns token",
+			withRawSink: true,
+			transformer: base64Decode,
+			raw:         []byte(base64.StdEncoding.EncodeToString([]byte(`{"access_token":"token-123"}`))),
+			want:        Token{AccessToken: "token-123"},
+			wantRaw:     []byte(base64.StdEncoding.EncodeToString([]byte(`{"access_token":"token-123"}`))),
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "unmarshaler set, parses secret with unmarshaler and returns token",
+			unmarshaler: &mockUnmarshaler{},
+			raw:         []byte(`{"access_token":"token-123"}`),
+			want:        Token{AccessToken: "token-123"},
+			wantCalls:   2,
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "unmarshaler set, unknown field, parses secret and extra field with unmarshaler and returns token",
+			unmarshaler: &mockUnmarshaler{},
+			raw:         []byte(`{"access_token":"token-123","rotated_by":"tool"}`),
+			want:        Token{AccessToken: "token-123", Extra: map[string]string{"rotated_by": "tool"}},
+			wantCalls:   3,
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "unmarshaler set, invalid secret, returns error",
+			unmarshaler: &mockUnmarshaler{},
+			raw:         []byte(`{invalid-json]`),
+			wantCalls:   1,
+			wantErr:     assert.Error,
+		},
+		{
+			name:    "path set, nested object, returns token",
+			path:    "credentials.api",
+			raw:     []byte(`{"credentials":{"api":{"access_token":"token-123","token_type":"bearer"}},"other":"value"}`),
+			want:    Token{AccessToken: "token-123", TokenType: "bearer"},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "path set, nested string, returns token with string as access token",
+			path:    "credentials.api.token",
+			raw:     []byte(`{"credentials":{"api":{"token":"token-123"}}}`),
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "path set, missing field, returns malformed secret error",
+			path: "credentials.api.token",
+			raw:  []byte(`{"credentials":{"db":{"token":"token-123"}}}`),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...) &&
+					assert.ErrorContains(t, err, "secret JSON path credentials.api not found", i...)
+			},
+		},
+		{
+			name: "path set, field is not an object, returns malformed secret error",
+			path: "credentials.api.token",
+			raw:  []byte(`{"credentials":{"api":"token-123"}}`),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
+		{
+			name:    "path and strict set, unknown field outside path, returns token",
+			path:    "credentials",
+			strict:  true,
+			raw:     []byte(`{"credentials":{"access_token":"token-123"},"rotated_by":"tool"}`),
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRaw []byte
+			d := secretDecoder{strict: tt.strict, transformer: tt.transformer}
+			if tt.withRawSink {
+				d.rawSink = func(raw []byte) { gotRaw = raw }
+			}
+			if tt.unmarshaler != nil {
+				d.unmarshaler = tt.unmarshaler
+			}
+			if tt.path != "" {
+				d.path = strings.Split(tt.path, ".")
+			}
+
+			got, err := d.decode(tt.raw)
+			assert.Equalf(t, tt.wantRaw, gotRaw, "decode(%s)", tt.raw)
+			if tt.unmarshaler != nil {
+				assert.Equalf(t, tt.wantCalls, tt.unmarshaler.calls, "decode(%s) unmarshaler calls", tt.raw)
+			}
+			if !tt.wantErr(t, err, fmt.Sprintf("decode(%s)", tt.raw)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "decode(%s)", tt.raw)
+		})
+	}
+}
+
+func TestWithSecretJSONPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{name: "dotted path, sets path segments", path: "credentials.api.token", want: []string{"credentials", "api", "token"}},
+		{name: "single field, sets path of field", path: "credentials", want: []string{"credentials"}},
+		{name: "empty path, sets no path", path: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, newConfig(WithSecretJSONPath(tt.path)).decoder.path, "WithSecretJSONPath(%q)", tt.path)
+		})
+	}
+
+	t.Run("empty path, returns token from top level of secret", func(t *testing.T) {
+		got, err := newConfig(WithSecretJSONPath("")).decoder.decode([]byte(`{"access_token":"token-123"}`))
+		assert.NoErrorf(t, err, "decode()")
+		assert.Equalf(t, Token{AccessToken: "token-123"}, got, "decode()")
+	})
+}
+
+func Test_secretDecoder_decodeNamed(t *testing.T) {
+	tests := []struct {
+		name        string
+		withRawSink bool
+		strict      bool
+		transformer func(raw []byte) ([]byte, error)
+		unmarshaler *mockUnmarshaler
+		wantCalls   int
+		raw         []byte
+		want        map[string]Token
+		wantRaw     []byte
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name: "valid secret, returns tokens",
+			raw:  []byte(`{"a":{"access_token":"token-a"},"b":{"access_token":"token-b","expiry":1893542400}}`),
+			want: map[string]Token{
+				"a": {AccessToken: "token-a"},
+				"b": {AccessToken: "token-b", Expiry: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "invalid secret, returns malformed secret error",
+			raw:  []byte(`{"a":"token-a"}`),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
+		{
+			name:    "strict, known fields, returns tokens",
+			strict:  true,
+			raw:     []byte(`{"a":{"access_token":"token-a","token_type":"bearer"}}`),
+			want:    map[string]Token{"a": {AccessToken: "token-a", TokenType: "bearer"}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "strict, unknown field, returns error",
+			strict:  true,
+			raw:     []byte(`{"a":{"access_token":"token-a","rotated_by":"tool"}}`),
+			wantErr: assert.Error,
+		},
+		{
+			name:        "raw sink set, passes raw secret to sink and returns tokens",
+			withRawSink: true,
+			raw:         []byte(`{"a":{"access_token":"token-a"}}`),
+			want:        map[string]Token{"a": {AccessToken: "token-a"}},
+			wantRaw:     []byte(`{"a":{"access_token":"token-a"}}`),
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "transformer set, transforms secret and returns tokens",
+			transformer: base64Decode,
+			raw:         []byte(base64.StdEncoding.EncodeToString([]byte(`{"a":{"access_token":"token-a"}}`))),
+			want:        map[string]Token{"a": {AccessToken: "token-a"}},
+			wantErr:     assert.NoError,
+		},
+		{
+			name:        "transformer returns error, returns error",
+			transformer: func(raw []byte) ([]byte, error) { return nil, errors.New("error") },
+			raw:         []byte(`{"a":{"access_token":"token-a"}}`),
+			wantErr:     assert.Error,
+		},
+		{
+			name:        "unmarshaler set, parses secret and tokens with unmarshaler and returns tokens",
+			unmarshaler: &mockUnmarshaler{},
+			raw:         []byte(`{"a":{"access_token":"token-a"}}`),
+			want:        map[string]Token{"a": {AccessToken: "token-a"}},
+			wantCalls:   3,
+			wantErr:     assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRaw []byte
+			d := secretDecoder{strict: tt.strict, transformer: tt.transformer}
+			if tt.withRawSink {
+				d.rawSink = func(raw []byte) { gotRaw = raw }
+			}
+			if tt.unmarshaler != nil {
+				d.unmarshaler = tt.unmarshaler
+			}
+
+			got, err := d.decodeNamed(tt.raw)
+			assert.Equalf(t, tt.wantRaw, gotRaw, "decodeNamed(%s)", tt.raw)
+			if tt.unmarshaler != nil {
+				assert.Equalf(t, tt.wantCalls, tt.unmarshaler.calls, "decodeNamed(%s) unmarshaler calls", tt.raw)
+			}
+			if !tt.wantErr(t, err, fmt.Sprintf("decodeNamed(%s)", tt.raw)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "decodeNamed(%s)", tt.raw)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrFetcherClosed is returned when fetching a token from a Fetcher that has been closed
+	ErrFetcherClosed = errors.New("fetcher closed")
+
+	// ErrCircuitOpen is returned when a refresh is skipped because the circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker open")
+
+	// ErrTokenNotFound is returned when a MultiFetcher has no token with the requested name
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrTokenExpired is returned when a fetched token is already expired
+	ErrTokenExpired = errors.New("token expired")
+
+	// ErrExpiryBufferExceedsLifetime is returned when the lifetime of a fetched token is no longer than the expiry buffer,
+	// so the token would be refreshed on every fetch
+	ErrExpiryBufferExceedsLifetime = errors.New("expiry buffer exceeds token lifetime")
+
+	// ErrBelowMinUsableLifetime is passed to the warning callback when a fetched token already expires within the min
+	// usable lifetime set by WithMinUsableLifetime, so the token would be refreshed on every fetch
+	ErrBelowMinUsableLifetime = errors.New("token lifetime below min usable lifetime")
+
+	// ErrClockMovedBackwards is passed to the warning callback when the clock moves backwards by more than the threshold
+	// set by WithClockJumpDetection
+	ErrClockMovedBackwards = errors.New("clock moved backwards")
+
+	// ErrAssumeRole is returned when the role used to read a secret can't be assumed
+	ErrAssumeRole = errors.New("unable to assume role")
+
+	// ErrSecretNotFound is returned when the secret holding a token doesn't exist
+	ErrSecretNotFound = errors.New("secret not found")
+
+	// ErrAccessDenied is returned when access to the secret holding a token is denied
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrRegionUnavailable is returned when the region of the secret holding a token can't be reached or fails to serve
+	// the request, as opposed to the secret being missing or access denied
+	ErrRegionUnavailable = errors.New("region unavailable")
+
+	// ErrEmptySecret is returned when the secret holding a token has no value, e.g. an empty secret version or service
+	// account token file
+	ErrEmptySecret = errors.New("secret is empty")
+
+	// ErrMalformedSecret is returned when the secret value read by a built-in adapter can't be parsed into a token
+	ErrMalformedSecret = errors.New("malformed secret")
+
+	// ErrResponseTooLarge is returned when a token source is larger than the size set by WithMaxResponseSize
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// sourceError wraps the error of an adapter operation with the operation and the identifier of the source, e.g.
+// "unable to fetch token from secretsmanager[my/secret]: <cause>", so the failing source can be identified in logs
+func sourceError(op string, source string, err error) error {
+	return fmt.Errorf("unable to %s from %s: %w", op, source, err)
+}
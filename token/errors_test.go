@@ -0,0 +1,56 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_sourceError(t *testing.T) {
+	errCause := errors.New("cause")
+	missingPath := filepath.Join(t.TempDir(), "token")
+
+	tests := []struct {
+		name       string
+		adapter    Adapter
+		wantSource string
+		wantIs     error
+	}{
+		{
+			name:       "kubernetes adapter, missing file, returns error with path",
+			adapter:    kubernetesSATokenAdapter{path: missingPath},
+			wantSource: "unable to read token from file[" + missingPath + "]: ",
+			wantIs:     fs.ErrNotExist,
+		},
+		{
+			name:       "fs adapter, missing file, returns error with name",
+			adapter:    NewFSAdapter(fstest.MapFS{}, "token.json"),
+			wantSource: "unable to read token from fs[token.json]: ",
+			wantIs:     fs.ErrNotExist,
+		},
+		{
+			name:       "reader adapter, open fails, returns error with reader",
+			adapter:    NewReaderAdapter(func() (io.ReadCloser, error) { return nil, errCause }),
+			wantSource: "unable to read token from reader: ",
+			wantIs:     errCause,
+		},
+		{
+			name:       "func adapter, fetch fails, returns error with func",
+			adapter:    NewFuncAdapter(func(ctx context.Context) ([]byte, error) { return nil, errCause }),
+			wantSource: "unable to fetch token from func: ",
+			wantIs:     errCause,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.adapter.Fetch(context.Background())
+			assert.ErrorContainsf(t, err, tt.wantSource, "Fetch()")
+			assert.ErrorIsf(t, err, tt.wantIs, "Fetch()")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"sync"
+	"time"
+)
+
+// NewFailoverAdapter returns an Adapter fetching tokens from the primary Adapter, failing over to the secondary Adapter
+// once the primary has failed threshold consecutive times. While failed over, the primary is probed at most once per
+// probeInterval, failing back to it once it succeeds.
+func NewFailoverAdapter(primary, secondary Adapter, threshold int, probeInterval time.Duration) Adapter {
+	return &failoverAdapter{
+		primary:       primary,
+		secondary:     secondary,
+		threshold:     max(threshold, 1),
+		probeInterval: probeInterval,
+		clock:         clock.NewSystem(),
+	}
+}
+
+type failoverAdapter struct {
+	primary       Adapter
+	secondary     Adapter
+	threshold     int
+	probeInterval time.Duration
+	clock         clock.Clock
+
+	mu         sync.Mutex
+	failures   int
+	failedOver bool
+	lastProbe  time.Time
+}
+
+func (a *failoverAdapter) Fetch(ctx context.Context) (Token, error) {
+	if !a.usePrimary() {
+		return a.secondary.Fetch(ctx)
+	}
+
+	t, err := a.primary.Fetch(ctx)
+	if !a.recordPrimary(err) {
+		return t, err
+	}
+
+	t, secondaryErr := a.secondary.Fetch(ctx)
+	if secondaryErr != nil {
+		return Token{}, errors.Join(err, secondaryErr)
+	}
+	return t, nil
+}
+
+// usePrimary reports whether the primary should be called, either because it hasn't failed over or to probe it
+func (a *failoverAdapter) usePrimary() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.failedOver {
+		return true
+	}
+	now := a.clock.Now()
+	if now.Before(a.lastProbe.Add(a.probeInterval)) {
+		return false
+	}
+	a.lastProbe = now
+	return true
+}
+
+// recordPrimary records the outcome of calling the primary, and reports whether the secondary should be called
+func (a *failoverAdapter) recordPrimary(err error) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err == nil {
+		a.failures = 0
+		a.failedOver = false
+		return false
+	}
+
+	a.failures++
+	if a.failures >= a.threshold && !a.failedOver {
+		a.failedOver = true
+		a.lastProbe = a.clock.Now()
+	}
+	return a.failedOver
+}
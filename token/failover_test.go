@@ -0,0 +1,193 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestNewFailoverAdapter(t *testing.T) {
+	primary, secondary := new(mockAdapter), new(mockAdapter)
+
+	tests := []struct {
+		name          string
+		threshold     int
+		wantThreshold int
+	}{
+		{name: "NewFailoverAdapter returns adapter with threshold", threshold: 3, wantThreshold: 3},
+		{name: "NewFailoverAdapter returns adapter with minimum threshold", threshold: 0, wantThreshold: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NewFailoverAdapter(primary, secondary, tt.threshold, time.Minute).(*failoverAdapter)
+			if !assert.Truef(t, ok, "NewFailoverAdapter() adapter type") {
+				return
+			}
+			assert.Equalf(t, primary, got.primary, "NewFailoverAdapter()")
+			assert.Equalf(t, secondary, got.secondary, "NewFailoverAdapter()")
+			assert.Equalf(t, tt.wantThreshold, got.threshold, "NewFailoverAdapter()")
+			assert.Equalf(t, time.Minute, got.probeInterval, "NewFailoverAdapter()")
+		})
+	}
+}
+
+func Test_failoverAdapter_Fetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	primaryTok := Token{AccessToken: "primary-token-123"}
+	secondaryTok := Token{AccessToken: "secondary-token-123"}
+
+	type fields struct {
+		failures   int
+		failedOver bool
+		lastProbe  time.Time
+	}
+	type mockOpts struct {
+		primary   func(m *mockAdapter)
+		secondary func(m *mockAdapter)
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		mockOpts       mockOpts
+		want           Token
+		wantFailures   int
+		wantFailedOver bool
+		wantLastProbe  time.Time
+		wantErr        assert.ErrorAssertionFunc
+	}{
+		{
+			name: "primary returns token, returns primary token",
+			mockOpts: mockOpts{primary: func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(primaryTok, nil).Once()
+			}},
+			want:    primaryTok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "primary returns token after failures, returns primary token and resets failures",
+			fields: fields{failures: 2},
+			mockOpts: mockOpts{primary: func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(primaryTok, nil).Once()
+			}},
+			want:    primaryTok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "primary returns error below threshold, returns error without calling secondary",
+			fields: fields{failures: 1},
+			mockOpts: mockOpts{primary: func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantFailures: 2,
+			wantErr:      assert.Error,
+		},
+		{
+			name:   "primary returns error reaching threshold, fails over and returns secondary token",
+			fields: fields{failures: 2},
+			mockOpts: mockOpts{
+				primary: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+				},
+				secondary: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(secondaryTok, nil).Once()
+				},
+			},
+			want:           secondaryTok,
+			wantFailures:   3,
+			wantFailedOver: true,
+			wantLastProbe:  now,
+			wantErr:        assert.NoError,
+		},
+		{
+			name:   "primary returns error reaching threshold, secondary returns error, returns error",
+			fields: fields{failures: 2},
+			mockOpts: mockOpts{
+				primary: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+				},
+				secondary: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+				},
+			},
+			wantFailures:   3,
+			wantFailedOver: true,
+			wantLastProbe:  now,
+			wantErr:        assert.Error,
+		},
+		{
+			name:   "failed over within probe interval, returns secondary token without calling primary",
+			fields: fields{failures: 3, failedOver: true, lastProbe: now.Add(-time.Second)},
+			mockOpts: mockOpts{secondary: func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(secondaryTok, nil).Once()
+			}},
+			want:           secondaryTok,
+			wantFailures:   3,
+			wantFailedOver: true,
+			wantLastProbe:  now.Add(-time.Second),
+			wantErr:        assert.NoError,
+		},
+		{
+			name:   "failed over after probe interval, primary returns token, fails back and returns primary token",
+			fields: fields{failures: 3, failedOver: true, lastProbe: now.Add(-time.Minute)},
+			mockOpts: mockOpts{primary: func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(primaryTok, nil).Once()
+			}},
+			want:          primaryTok,
+			wantLastProbe: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "failed over after probe interval, primary returns error, returns secondary token",
+			fields: fields{failures: 3, failedOver: true, lastProbe: now.Add(-time.Minute)},
+			mockOpts: mockOpts{
+				primary: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+				},
+				secondary: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(secondaryTok, nil).Once()
+				},
+			},
+			want:           secondaryTok,
+			wantFailures:   4,
+			wantFailedOver: true,
+			wantLastProbe:  now,
+			wantErr:        assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mPrimary, mSecondary := new(mockAdapter), new(mockAdapter)
+			if tt.mockOpts.primary != nil {
+				tt.mockOpts.primary(mPrimary)
+			}
+			if tt.mockOpts.secondary != nil {
+				tt.mockOpts.secondary(mSecondary)
+			}
+
+			a := &failoverAdapter{
+				primary:       mPrimary,
+				secondary:     mSecondary,
+				threshold:     3,
+				probeInterval: time.Minute,
+				clock:         clock.NewFixed(now),
+				failures:      tt.fields.failures,
+				failedOver:    tt.fields.failedOver,
+				lastProbe:     tt.fields.lastProbe,
+			}
+			got, err := a.Fetch(context.Background())
+			mPrimary.AssertExpectations(t)
+			mSecondary.AssertExpectations(t)
+			assert.Equalf(t, tt.wantFailures, a.failures, "Fetch()")
+			assert.Equalf(t, tt.wantFailedOver, a.failedOver, "Fetch()")
+			assert.Equalf(t, tt.wantLastProbe, a.lastProbe, "Fetch()")
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
@@ -2,33 +2,103 @@ package token
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/ellogroup/ello-golang-clock/clock"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Token represents an access token
-type Token struct {
-	AccessToken  string    `json:"access_token"`
-	TokenType    string    `json:"token_type,omitempty"`
-	RefreshToken string    `json:"refresh_token,omitempty"`
-	Expiry       time.Time `json:"expiry,omitempty"`
-	CreatedAt    time.Time `json:"created_at,omitempty"`
+// TokenFetcher fetches access tokens. It is satisfied by Fetcher, allowing consumers to depend on it and substitute a
+// mock in tests.
+type TokenFetcher interface {
+	Fetch(ctx context.Context, opts ...FetchOption) (Token, error)
 }
 
+var _ TokenFetcher = (*Fetcher)(nil)
+
 // Fetcher fetches access tokens stored by the Ello Token Rotator
 type Fetcher struct {
-	config  config
-	clock   clock.Clock
-	adapter Adapter
-	token   Token
+	mu              sync.Mutex
+	config          config
+	clock           clock.Clock
+	adapter         Adapter
+	token           Token
+	fetchedAt       time.Time
+	refreshAfter    time.Time
+	refreshAfterSet bool
+	lastChangeCheck time.Time
+	createdAtCheck  time.Time
+	introspectedAt  time.Time
+	lastNow         time.Time
+	closed          bool
+	rand            func() float64
+	refreshes       atomic.Uint64
+	stats           fetcherStats
+	lastAccess      time.Time
+	idleTimer       *time.Timer
+	refreshing      bool
+	started         bool
+	startupDeadline time.Time
+	startup         chan struct{}
+
+	subsMu     sync.Mutex
+	subs       []chan Token
+	subsClosed bool
+
+	consecutiveFailures int
+	circuitOpenUntil    time.Time
+	lastRefreshErr      error
+	lastRefreshFailedAt time.Time
 }
 
 type config struct {
-	tokenExpiryBuffer time.Duration
+	name                 string
+	tokenExpiryBuffer    time.Duration
+	expiryBufferFraction float64
+	clockSkew            time.Duration
+	maxTokenAge          time.Duration
+	minUsableLifetime    time.Duration
+	startupWindow        time.Duration
+	startupDelay         time.Duration
+	backoff              BackoffStrategy
+	changeCheckInterval  time.Duration
+	createdAtInterval    time.Duration
+	circuitFailures      int
+	circuitCooldown      time.Duration
+	minRefreshInterval   time.Duration
+	refreshAheadFraction float64
+	disableCache         bool
+	asyncRefresh         bool
+	idleEviction         time.Duration
+	rejectExpiredTokens  bool
+	strictExpiryBuffer   bool
+	reuseOnParseError    bool
+	initialToken         Token
+	postProcessor        func(t Token) (Token, error)
+	refreshPredicate     func(current Token, now time.Time) bool
+	tracer               Tracer
+	refreshQueue         *RefreshQueue
+	onRefreshError       func(err error, consecutiveFailures int)
+	onServe              func(remainingValidity time.Duration)
+	onWarning            func(err error)
+	observer             FetchObserver
+	logger               *slog.Logger
+	contextLogger        func(ctx context.Context) *slog.Logger
+	panicRecovery        bool
+	onPanic              func(recovered any)
+	decoder              secretDecoder
+	maxResponseSize      int64
+	rotationExpiry       bool
+	clockDrift           clockDriftCheck
+	clockJumpThreshold   time.Duration
+	smOptFns             []func(*secretsmanager.Options)
+	introspection        introspection
+	now                  func() time.Time
 }
 
 var defaultConfig = config{
@@ -37,79 +107,715 @@ var defaultConfig = config{
 
 type Option func(*config)
 
+// WithName labels the Fetcher, e.g. "service-a", to attribute its errors, warnings and spans when running several
+// fetchers
+func WithName(name string) Option {
+	return func(c *config) { c.name = name }
+}
+
 // WithTokenExpiryBuffer sets the duration before the expiry date when a token should be refreshed
 func WithTokenExpiryBuffer(buffer time.Duration) Option {
 	return func(c *config) { c.tokenExpiryBuffer = buffer }
 }
 
+// WithExpiryBufferFraction sets the duration before the expiry date when a token should be refreshed as a fraction of
+// its lifetime, from its created date to its expiry date, so one config suits tokens with widely varying lifetimes. The
+// fixed expiry buffer is used for tokens without a created date.
+func WithExpiryBufferFraction(fraction float64) Option {
+	return func(c *config) { c.expiryBufferFraction = min(max(fraction, 0), 1) }
+}
+
+// WithClockSkew sets the tolerated drift between the local clock and the clock of the token issuer. Tokens are treated
+// as valid for up to the skew past their expiry date, so a fast local clock doesn't discard freshly issued tokens. This
+// is independent of the expiry buffer, which refreshes tokens ahead of their expiry date.
+func WithClockSkew(skew time.Duration) Option {
+	return func(c *config) { c.clockSkew = skew }
+}
+
+// WithMaxTokenAge sets the maximum age of a token before it should be refreshed, regardless of its expiry date. The age
+// is measured from the token's created date, or from when it was fetched if the token has no created date.
+func WithMaxTokenAge(age time.Duration) Option {
+	return func(c *config) { c.maxTokenAge = age }
+}
+
+// WithRefreshPredicate sets a function reporting whether the cached token should be refreshed, in addition to the
+// built-in checks, e.g. to refresh when an application-specific signal shows the credential is no longer trusted. It is
+// only called for tokens the built-in checks consider valid, while the Fetcher is locked, so must not call the Fetcher.
+func WithRefreshPredicate(fn func(current Token, now time.Time) bool) Option {
+	return func(c *config) { c.refreshPredicate = fn }
+}
+
+// WithMinUsableLifetime refreshes the cached token if it would expire within the lifetime, so callers doing long
+// operations are served a token valid for at least that long regardless of the expiry buffer. A refreshed token is
+// served even if its lifetime is shorter, but is passed to the warning callback wrapped in ErrBelowMinUsableLifetime, as
+// it would be refreshed again on every fetch.
+func WithMinUsableLifetime(lifetime time.Duration) Option {
+	return func(c *config) { c.minUsableLifetime = lifetime }
+}
+
+// WithChangeDetection checks adapters implementing ChangeDetector for changes at source at most once per interval,
+// refreshing the token when it has changed even if the cached token has not expired
+func WithChangeDetection(interval time.Duration) Option {
+	return func(c *config) { c.changeCheckInterval = interval }
+}
+
+// WithInitialToken seeds the cache with a token, e.g. one handed over from a previous process, avoiding a refresh on
+// the first fetch if the token is still valid
+func WithInitialToken(t Token) Option {
+	return func(c *config) { c.initialToken = t }
+}
+
+// WithOnRefreshError sets a callback invoked each time a refresh fails, with the number of consecutive failures so
+// callers can alert once failures persist. The count resets after a successful refresh. The callback is invoked while
+// the Fetcher is locked, so must not call the Fetcher.
+func WithOnRefreshError(fn func(err error, consecutiveFailures int)) Option {
+	return func(c *config) { c.onRefreshError = fn }
+}
+
+// WithOnServe sets a callback invoked each time a token is served, whether cached or refreshed, with its remaining
+// validity clamped at zero, e.g. to record a gauge of the headroom before expiry. It isn't invoked for tokens without an
+// expiry date. The callback is invoked while the Fetcher is locked, so must not call the Fetcher.
+func WithOnServe(fn func(remainingValidity time.Duration)) Option {
+	return func(c *config) { c.onServe = fn }
+}
+
+// WithOnWarning sets a callback invoked with conditions that don't fail a fetch but indicate a problem, e.g. a token
+// fetched already expired, wrapped in ErrTokenExpired, which is refreshed again on the next fetch. The callback is
+// invoked while the Fetcher is locked, so must not call the Fetcher.
+func WithOnWarning(fn func(err error)) Option {
+	return func(c *config) { c.onWarning = fn }
+}
+
+// WithSecretCacheReuseOnParseError keeps serving the cached token while it has not expired if a refresh fails with
+// ErrMalformedSecret, e.g. while a rotation has written a malformed secret value, until a later refresh succeeds. The
+// failure is passed to the warning callback rather than returned, and still counts towards the circuit breaker.
+func WithSecretCacheReuseOnParseError() Option {
+	return func(c *config) { c.reuseOnParseError = true }
+}
+
+// WithRejectExpiredTokens fails refreshes fetching a token that is already expired with ErrTokenExpired, rather than
+// returning the expired token. The failure counts towards the circuit breaker like any other refresh failure.
+func WithRejectExpiredTokens() Option {
+	return func(c *config) { c.rejectExpiredTokens = true }
+}
+
+// WithStrictExpiryBuffer fails refreshes fetching a token whose lifetime, from its created date to its expiry date, is
+// no longer than the expiry buffer with ErrExpiryBufferExceedsLifetime, rather than warning about it. Such a token
+// requires a refresh as soon as it is fetched, so is refreshed on every fetch. The failure counts towards the circuit
+// breaker like any other refresh failure.
+func WithStrictExpiryBuffer() Option {
+	return func(c *config) { c.strictExpiryBuffer = true }
+}
+
+// WithCircuitBreaker opens a circuit breaker after the given number of consecutive refresh failures. While open,
+// refreshes don't call the adapter: fetches are served the cached token as a cache hit while it is still valid, and
+// fail fast with ErrCircuitOpen otherwise or if forced. After the cooldown a single refresh is attempted, closing the
+// circuit if it succeeds or reopening it if it fails.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *config) {
+		c.circuitFailures = failures
+		c.circuitCooldown = cooldown
+	}
+}
+
+// WithMinRefreshInterval sets the minimum interval between refresh attempts after a failed refresh. Until it has
+// elapsed, fetches return the cached token as a cache hit if it has not yet expired, or the error of the failed refresh
+// otherwise or if forced, rather than calling the adapter.
+func WithMinRefreshInterval(interval time.Duration) Option {
+	return func(c *config) { c.minRefreshInterval = interval }
+}
+
+// WithDisableCache makes every fetch call the adapter rather than returning a cached token, e.g. to validate credentials
+// in a short-lived process. Concurrent fetches still share a single adapter call: fetches waiting on a refresh return
+// its result.
+func WithDisableCache() Option {
+	return func(c *config) { c.disableCache = true }
+}
+
 // New returns a new Fetcher with the provided Adapter
 func New(adapter Adapter, opts ...Option) *Fetcher {
+	return newFetcher(adapter, newConfig(opts...))
+}
+
+func newConfig(opts ...Option) config {
 	c := defaultConfig
 	for _, opt := range opts {
 		opt(&c)
 	}
-	return &Fetcher{
+	return c.observe().recoverPanics()
+}
+
+func newFetcher(adapter Adapter, c config) *Fetcher {
+	f := &Fetcher{
 		config:  c,
-		clock:   clock.NewSystem(),
+		clock:   c.clock(),
 		adapter: adapter,
+		token:   c.initialToken,
+		rand:    newRand(),
 	}
+	if f.token.AccessToken != "" {
+		f.fetchedAt = f.clock.Now()
+	}
+	return f
 }
 
-// NewAWSSecretsManagerFetcher returns a new Fetcher with the awsSecretsManagerClient Adapter
-func NewAWSSecretsManagerFetcher(smClient *secretsmanager.Client, smKey string, opts ...Option) *Fetcher {
-	return New(awsSecretsManagerAdapter{
-		client: smClient,
-		key:    smKey,
-	},
-		opts...,
-	)
+// FetchMeta describes how a token was obtained by FetchWithMeta
+type FetchMeta struct {
+	// Refreshed is true if the token was fetched from the adapter rather than served from the cache, which it is while
+	// refreshes are held back by the circuit breaker or the minimum refresh interval
+	Refreshed bool
+	// Latency is the time taken to obtain the token, including waiting for other fetches
+	Latency time.Duration
+	// Age is the time since the token was created, or zero if the token has no CreatedAt
+	Age time.Duration
+	// RemainingValidity is the time until the token expires, ignoring the expiry buffer, or zero if the token has no
+	// Expiry or has expired
+	RemainingValidity time.Duration
 }
 
-func (f *Fetcher) Fetch(ctx context.Context) (Token, error) {
-	if f.refreshRequired() {
-		return f.refresh(ctx)
+// withToken returns m with the Age and RemainingValidity of t at now
+func (m FetchMeta) withToken(t Token, now time.Time) FetchMeta {
+	if !t.CreatedAt.IsZero() && now.After(t.CreatedAt) {
+		m.Age = now.Sub(t.CreatedAt)
+	}
+	if !t.Expiry.IsZero() && t.Expiry.After(now) {
+		m.RemainingValidity = t.Expiry.Sub(now)
 	}
-	return f.token, nil
+	return m
 }
 
-func (f *Fetcher) refreshRequired() bool {
-	return f.token.AccessToken == "" || (!f.token.Expiry.IsZero() && f.token.Expiry.Before(f.clock.Now().Add(f.config.tokenExpiryBuffer)))
+// fetchOptions are the options of a single fetch
+type fetchOptions struct {
+	forceRefresh bool
+	timeout      time.Duration
+}
+
+// FetchOption sets an option of a single fetch, without changing the config of the Fetcher
+type FetchOption func(*fetchOptions)
+
+// ForceRefresh refreshes the token, even if the cached token is valid
+func ForceRefresh() FetchOption {
+	return func(o *fetchOptions) { o.forceRefresh = true }
+}
+
+// FetchTimeout sets the timeout of the adapter call if the token is refreshed
+func FetchTimeout(timeout time.Duration) FetchOption {
+	return func(o *fetchOptions) { o.timeout = timeout }
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, opts ...FetchOption) (Token, error) {
+	t, _, err := f.FetchWithMeta(ctx, opts...)
+	return t, err
+}
+
+// FetchWithMeta returns a token along with FetchMeta describing how it was obtained, e.g. to measure the cache hit rate.
+// A token carried by the context, see ContextWithToken, is returned without consulting the cache or adapter.
+func (f *Fetcher) FetchWithMeta(ctx context.Context, opts ...FetchOption) (Token, FetchMeta, error) {
+	var o fetchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if t, ok := TokenFromContext(ctx); ok {
+		return t.Clone(), FetchMeta{}.withToken(t, f.clock.Now()), nil
+	}
+
+	start := f.clock.Now()
+	refreshes := f.refreshes.Load()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, refreshed, err := f.fetch(ctx, refreshes, o)
+	now := f.clock.Now()
+	meta := FetchMeta{Refreshed: refreshed, Latency: now.Sub(start)}.withToken(t, now)
+	return t.Clone(), meta, err
+}
+
+// fetch returns the cached token, refreshing it if required, and whether it was refreshed. refreshes is the number of
+// refreshes completed before the fetch waited for the lock.
+func (f *Fetcher) fetch(ctx context.Context, refreshes uint64, o fetchOptions) (Token, bool, error) {
+	if f.closed {
+		return Token{}, false, ErrFetcherClosed
+	}
+	f.touch()
+	if f.config.disableCache && !o.forceRefresh && f.refreshes.Load() != refreshes &&
+		(f.lastRefreshErr != nil || f.token.AccessToken != "") {
+		// A refresh completed while waiting for the lock, so share its result rather than calling the adapter again. The
+		// count also changes if the Fetcher is reset, which leaves no result to share.
+		if f.lastRefreshErr != nil {
+			return Token{}, false, f.lastRefreshErr
+		}
+		f.hit()
+		return f.token, false, nil
+	}
+	forced := o.forceRefresh || f.config.disableCache
+	jumped := f.clockMovedBackwards(ctx)
+	required, synchronous := f.refreshRequired()
+	// A token changed at source, a revoked token, or one that may have expired before the clock moved backwards, can't
+	// be served while refreshing in the background
+	synchronous = forced || jumped || synchronous || f.sourceChanged(ctx)
+	revoked := !required && !synchronous && f.revoked(ctx)
+	if required || synchronous || revoked {
+		if !synchronous && !revoked && f.refreshAsync(ctx, o.timeout) {
+			f.hit()
+			return f.token, false, nil
+		}
+		held := !forced && !jumped && !revoked && f.refreshHeldBack()
+		if held && f.token.Valid(f.clock.Now().Add(-f.config.clockSkew), 0) {
+			// Refreshes are held back, so serve the cached token while it is still valid rather than failing
+			f.hit()
+			return f.token, false, nil
+		}
+		if o.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, o.timeout)
+			defer cancel()
+		}
+		t, err := f.refresh(ctx)
+		if err == nil {
+			f.served(t)
+		}
+		return t, true, err
+	}
+	if t, ok := f.pollCreatedAt(ctx); ok {
+		f.served(t)
+		return t, true, nil
+	}
+	f.hit()
+	return f.token, false, nil
+}
+
+// hit records the cached token being served without waiting for a refresh
+func (f *Fetcher) hit() {
+	f.stats.hits.Add(1)
+	f.observe(func(obs FetchObserver) { obs.OnCacheHit(f.token) })
+	f.served(f.token)
+}
+
+func (f *Fetcher) served(t Token) {
+	if f.config.onServe == nil || t.Expiry.IsZero() {
+		return
+	}
+	f.config.onServe(max(f.clock.Until(t.Expiry), 0))
+}
+
+// Close releases the resources held by the Fetcher, closing the adapter if it implements io.Closer. Subsequent fetches
+// return ErrFetcherClosed. Close is safe to call multiple times.
+func (f *Fetcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	f.setToken(Token{}, time.Time{})
+	if f.idleTimer != nil {
+		f.idleTimer.Stop()
+	}
+	f.closeSubscribers()
+
+	if c, ok := f.adapter.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("unable to close adapter: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetTokenExpiryBuffer updates the duration before the expiry date when a token should be refreshed. It is safe to call
+// while the Fetcher is in use, e.g. on a config reload.
+func (f *Fetcher) SetTokenExpiryBuffer(buffer time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config.tokenExpiryBuffer = buffer
+	f.refreshAfterSet = false
+}
+
+// Invalidate clears the cached token, so the next fetch refreshes it, e.g. after the token was rejected with a 401
+func (f *Fetcher) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setToken(Token{}, time.Time{})
+}
+
+// InvalidateIf clears the cached token only if it carries the same credential as t, as reported by
+// Token.SameCredential, returning whether it was cleared. Callers retrying after a 401 should pass the rejected token,
+// so a token already replaced by a concurrent refresh, including one with only a rotated refresh token, isn't
+// invalidated, avoiding a refresh for each concurrent 401.
+func (f *Fetcher) InvalidateIf(t Token) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token.AccessToken == "" || !f.token.SameCredential(t) {
+		return false
+	}
+	f.setToken(Token{}, time.Time{})
+	return true
+}
+
+// Reset clears the cached token, failure counters, stats and circuit breaker state, returning the Fetcher to its state
+// when constructed while keeping its config and adapter, e.g. to reuse a Fetcher across test cases. A closed Fetcher
+// remains closed.
+func (f *Fetcher) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.setToken(f.config.initialToken, time.Time{})
+	if f.token.AccessToken != "" {
+		f.fetchedAt = f.clock.Now()
+	}
+	f.lastChangeCheck = time.Time{}
+	f.createdAtCheck = time.Time{}
+	f.introspectedAt = time.Time{}
+	f.lastNow = time.Time{}
+	f.lastAccess = time.Time{}
+	f.consecutiveFailures = 0
+	f.circuitOpenUntil = time.Time{}
+	f.lastRefreshErr = nil
+	f.lastRefreshFailedAt = time.Time{}
+	f.started = false
+	f.startupDeadline = time.Time{}
+	f.stats.reset()
+	f.refreshes.Store(0)
+}
+
+// Prefetch concurrently fetches a token with each of the fetchers, e.g. to warm them during startup. The errors of any
+// fetchers that failed are joined and returned.
+func Prefetch(ctx context.Context, fetchers ...*Fetcher) error {
+	errs := make([]error, len(fetchers))
+	var wg sync.WaitGroup
+	for i, f := range fetchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = f.Fetch(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Healthy returns nil if a valid token is cached or can be fetched, suitable for readiness and liveness probes
+func (f *Fetcher) Healthy(ctx context.Context) error {
+	t, err := f.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to fetch token: %w", err)
+	}
+	if !t.Expiry.IsZero() && !t.Expiry.After(f.clock.Now().Add(-f.config.clockSkew)) {
+		return fmt.Errorf("token expired at %s", t.Expiry.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Validate fetches a token from the adapter without caching it, to confirm connectivity and config at startup, e.g. to
+// fail fast on a wrong secret key or missing permissions. Errors wrap the underlying typed errors, such as
+// ErrSecretNotFound or ErrAccessDenied, so the misconfiguration can be identified.
+func (f *Fetcher) Validate(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return ErrFetcherClosed
+	}
+	t, err := f.startupFetch(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to validate adapter: %w", err)
+	}
+	if t.Expired(f.clock.Now().Add(-f.config.clockSkew)) {
+		return fmt.Errorf("unable to validate adapter: %w at %s", ErrTokenExpired, t.Expiry.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// refreshRequired reports whether the cached token requires a refresh, and whether the refresh must complete before a
+// token is served, as there's no cached token or the refresh predicate requires it. The instant after which the cached
+// token requires a refresh is cached until the token or config changes, so fetches of a valid cached token only compare
+// it with the time.
+func (f *Fetcher) refreshRequired() (required, synchronous bool) {
+	if f.token.AccessToken == "" {
+		return true, true
+	}
+	if !f.refreshAfterSet {
+		f.refreshAfter, f.refreshAfterSet = f.config.refreshAfter(f.token, f.fetchedAt), true
+	}
+
+	now := f.clock.Now()
+	if f.config.refreshPredicate != nil && f.config.refreshPredicate(f.token, now) {
+		return true, true
+	}
+	return !f.refreshAfter.IsZero() && now.After(f.refreshAfter) || f.refreshAhead(now), false
+}
+
+// setToken caches the token fetched at fetchedAt, clearing the instant after which the cached token requires a refresh
+func (f *Fetcher) setToken(t Token, fetchedAt time.Time) {
+	f.token = t
+	f.fetchedAt = fetchedAt
+	f.refreshAfterSet = false
+}
+
+// refreshAfter returns the instant after which the token requires a refresh due to its expiry date, the max token age
+// or the min usable lifetime, or the zero time if it never does. It must be kept consistent with config.refreshRequired
+// and belowMinUsableLifetime.
+func (c config) refreshAfter(t Token, fetchedAt time.Time) time.Time {
+	var after time.Time
+	earliest := func(at time.Time) {
+		if after.IsZero() || at.Before(after) {
+			after = at
+		}
+	}
+	if !t.Expiry.IsZero() {
+		earliest(t.Expiry.Add(c.clockSkew - c.expiryBuffer(t)))
+		if c.minUsableLifetime > 0 {
+			earliest(t.Expiry.Add(-c.minUsableLifetime))
+		}
+	}
+	if c.maxTokenAge > 0 {
+		issued := t.CreatedAt
+		if issued.IsZero() {
+			issued = fetchedAt
+		}
+		if !issued.IsZero() {
+			earliest(issued.Add(c.maxTokenAge + c.clockSkew))
+		}
+	}
+	return after
+}
+
+func (c config) refreshRequired(t Token, fetchedAt time.Time, now time.Time) bool {
+	skewed := now.Add(-c.clockSkew)
+	return !t.Valid(skewed, c.expiryBuffer(t)) || c.maxAgeExceeded(t, fetchedAt, skewed) ||
+		c.refreshPredicate != nil && c.refreshPredicate(t, now)
+}
+
+// expiryBuffer returns the duration before the expiry date of the token when it should be refreshed, the expiry buffer
+// fraction of its lifetime if set and the token has a created date, otherwise the fixed expiry buffer
+func (c config) expiryBuffer(t Token) time.Duration {
+	if c.expiryBufferFraction <= 0 || t.CreatedAt.IsZero() || t.Expiry.IsZero() {
+		return c.tokenExpiryBuffer
+	}
+	return time.Duration(c.expiryBufferFraction * float64(t.Expiry.Sub(t.CreatedAt)))
+}
+
+// belowMinUsableLifetime reports whether the token expires within the min usable lifetime, if set
+func (c config) belowMinUsableLifetime(t Token, now time.Time) bool {
+	if c.minUsableLifetime <= 0 || t.Expiry.IsZero() {
+		return false
+	}
+	return t.Expiry.Sub(now) < c.minUsableLifetime
+}
+
+func (c config) maxAgeExceeded(t Token, fetchedAt time.Time, now time.Time) bool {
+	if c.maxTokenAge <= 0 {
+		return false
+	}
+
+	issued := t.CreatedAt
+	if issued.IsZero() {
+		issued = fetchedAt
+	}
+	return !issued.IsZero() && now.Sub(issued) > c.maxTokenAge
+}
+
+// sourceChanged reports whether the adapter has detected a change at source. Detection is best effort, so a failed
+// check leaves the cached token in place.
+func (f *Fetcher) sourceChanged(ctx context.Context) bool {
+	d, ok := f.adapter.(ChangeDetector)
+	if !ok || f.config.changeCheckInterval <= 0 {
+		return false
+	}
+
+	now := f.clock.Now()
+	if now.Before(f.lastChangeCheck.Add(f.config.changeCheckInterval)) {
+		return false
+	}
+	f.lastChangeCheck = now
+
+	changed, err := d.Changed(ctx)
+	return err == nil && changed
 }
 
 func (f *Fetcher) refresh(ctx context.Context) (Token, error) {
-	t, err := f.adapter.Fetch(ctx)
+	if f.circuitOpen() {
+		return Token{}, fmt.Errorf("%w until %s", ErrCircuitOpen, f.circuitOpenUntil.Format(time.RFC3339))
+	}
+	if f.refreshThrottled() {
+		return Token{}, f.lastRefreshErr
+	}
+	if f.startup != nil {
+		// Another call is retrying within the startup window, so share its outcome rather than retrying alongside it
+		return f.awaitStartup(ctx)
+	}
+
+	start := f.clock.Now()
+	t, err := f.startupFetch(ctx)
+	return f.refreshed(ctx, start, t, err)
+}
+
+// refreshed records the outcome of an adapter call started at start, caching the token if it succeeded
+func (f *Fetcher) refreshed(ctx context.Context, start time.Time, t Token, err error) (Token, error) {
+	latency := f.clock.Since(start)
+	f.stats.recordLatency(latency)
+	f.refreshes.Add(1)
+	if err == nil {
+		err = f.checkExpired(ctx, t)
+	}
+	if err == nil {
+		err = f.checkLifetime(ctx, t)
+	}
+	if err == nil {
+		f.checkUsableLifetime(ctx, t)
+	}
 	if err != nil {
+		f.stats.failures.Add(1)
+		f.consecutiveFailures++
+		f.lastRefreshErr = err
+		f.lastRefreshFailedAt = f.clock.Now()
+		f.log(ctx, slog.LevelWarn, "token refresh failed", "err", err, "consecutive_failures", f.consecutiveFailures)
+		if f.config.circuitFailures > 0 && f.consecutiveFailures >= f.config.circuitFailures {
+			f.circuitOpenUntil = f.clock.Now().Add(f.config.circuitCooldown)
+			f.log(ctx, slog.LevelWarn, "token circuit breaker opened", "until", f.circuitOpenUntil)
+		}
+		if f.config.onRefreshError != nil {
+			f.config.onRefreshError(f.named(err), f.consecutiveFailures)
+		}
+		if f.reuseOnParseError(ctx, err) {
+			return f.token, nil
+		}
 		return Token{}, err
 	}
 
-	f.token = t
+	f.consecutiveFailures = 0
+	f.lastRefreshErr = nil
+	changed := !t.SameCredential(f.token)
+	f.setToken(t, f.clock.Now())
+	if f.config.changeCheckInterval > 0 {
+		f.lastChangeCheck = f.fetchedAt
+	}
+	if f.config.createdAtInterval > 0 {
+		f.createdAtCheck = f.fetchedAt
+	}
+	if f.config.introspection.url != "" {
+		f.introspectedAt = f.fetchedAt
+	}
+	f.log(ctx, slog.LevelDebug, "token refreshed", "expiry", t.Expiry)
+	f.observe(func(obs FetchObserver) { obs.OnRefresh(t, latency) })
+	if changed {
+		f.observe(func(obs FetchObserver) { obs.OnNewToken(t) })
+	}
+	f.publish(t)
 	return t, nil
 }
 
-type Adapter interface {
-	Fetch(ctx context.Context) (Token, error)
+// checkExpired warns about, or rejects, a fetched token that is already expired, as it would be refreshed again on
+// every fetch
+func (f *Fetcher) checkExpired(ctx context.Context, t Token) error {
+	if !t.Expired(f.clock.Now().Add(-f.config.clockSkew)) {
+		return nil
+	}
+
+	err := fmt.Errorf("%w at %s", ErrTokenExpired, t.Expiry.Format(time.RFC3339))
+	if f.config.rejectExpiredTokens {
+		return err
+	}
+	f.log(ctx, slog.LevelWarn, "fetched token already expired", "expiry", t.Expiry)
+	if f.config.onWarning != nil {
+		f.config.onWarning(f.named(err))
+	}
+	return nil
 }
 
-type awsSecretsManagerClient interface {
-	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+// checkLifetime warns about, or rejects, a fetched token whose lifetime is no longer than the expiry buffer, as it
+// would be refreshed again on every fetch
+func (f *Fetcher) checkLifetime(ctx context.Context, t Token) error {
+	if t.CreatedAt.IsZero() || t.Expiry.IsZero() {
+		return nil
+	}
+	buffer, lifetime := f.config.expiryBuffer(t), t.Expiry.Sub(t.CreatedAt)
+	if buffer < lifetime {
+		return nil
+	}
+
+	err := fmt.Errorf("%w: buffer %s, lifetime %s", ErrExpiryBufferExceedsLifetime, buffer, lifetime)
+	if f.config.strictExpiryBuffer {
+		return err
+	}
+	f.log(ctx, slog.LevelWarn, "expiry buffer exceeds token lifetime", "buffer", buffer, "lifetime", lifetime)
+	if f.config.onWarning != nil {
+		f.config.onWarning(f.named(err))
+	}
+	return nil
 }
-type awsSecretsManagerAdapter struct {
-	client awsSecretsManagerClient
-	key    string
+
+// checkUsableLifetime warns about a fetched token that already expires within the min usable lifetime, as it would be
+// refreshed again on every fetch. An expired token is already warned about by checkExpired.
+func (f *Fetcher) checkUsableLifetime(ctx context.Context, t Token) {
+	now := f.clock.Now()
+	if !f.config.belowMinUsableLifetime(t, now) || t.Expired(now.Add(-f.config.clockSkew)) {
+		return
+	}
+
+	lifetime, remaining := f.config.minUsableLifetime, t.Expiry.Sub(now)
+	err := fmt.Errorf("%w: min %s, remaining %s", ErrBelowMinUsableLifetime, lifetime, remaining)
+	f.log(ctx, slog.LevelWarn, "fetched token expires within min usable lifetime", "min", lifetime, "remaining", remaining)
+	if f.config.onWarning != nil {
+		f.config.onWarning(f.named(err))
+	}
 }
 
-func (a awsSecretsManagerAdapter) Fetch(ctx context.Context) (Token, error) {
-	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(a.key),
-	})
-	if err != nil {
-		return Token{}, fmt.Errorf("unable to fetch token from secrets manager: %w", err)
+// reuseOnParseError reports whether the cached token should be served despite the refresh failing to parse the secret
+// value, warning about the failure if so
+func (f *Fetcher) reuseOnParseError(ctx context.Context, err error) bool {
+	if !f.config.reuseOnParseError || !errors.Is(err, ErrMalformedSecret) ||
+		!f.token.Valid(f.clock.Now().Add(-f.config.clockSkew), 0) {
+		return false
 	}
 
-	var t Token
-	if err := json.Unmarshal([]byte(*out.SecretString), &t); err != nil {
-		return Token{}, fmt.Errorf("unable to parse token from secrets manager: %w", err)
+	f.log(ctx, slog.LevelWarn, "serving cached token after failing to parse secret", "err", err)
+	if f.config.onWarning != nil {
+		f.config.onWarning(f.named(err))
 	}
+	return true
+}
 
-	return t, nil
+// Adapter returns the adapter the Fetcher fetches tokens from, e.g. to inspect the adapter constructed for diagnostics
+func (f *Fetcher) Adapter() Adapter {
+	return f.adapter
+}
+
+// Name returns the name set by WithName
+func (f *Fetcher) Name() string {
+	return f.config.name
+}
+
+// named prefixes an error passed to a callback with the name of the Fetcher, if set
+func (f *Fetcher) named(err error) error {
+	if f.config.name == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", f.config.name, err)
+}
+
+// refreshThrottled reports whether a refresh failed within the minimum refresh interval
+func (f *Fetcher) refreshThrottled() bool {
+	return f.config.minRefreshInterval > 0 && f.lastRefreshErr != nil &&
+		f.clock.Now().Before(f.lastRefreshFailedAt.Add(f.config.minRefreshInterval))
+}
+
+// refreshHeldBack reports whether refreshes are held back by the circuit breaker or the minimum refresh interval
+func (f *Fetcher) refreshHeldBack() bool {
+	return f.circuitOpen() || f.refreshThrottled()
+}
+
+func (f *Fetcher) circuitOpen() bool {
+	return f.config.circuitFailures > 0 && f.clock.Now().Before(f.circuitOpenUntil)
+}
+
+type Adapter interface {
+	Fetch(ctx context.Context) (Token, error)
+}
+
+// ChangeDetector is implemented by adapters able to cheaply detect that the token at source has changed since it was
+// last fetched, e.g. after an out-of-band rotation
+type ChangeDetector interface {
+	Changed(ctx context.Context) (bool, error)
 }
@@ -4,11 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/ellogroup/ello-golang-clock/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"sync"
 	"testing"
 	"time"
 )
@@ -22,8 +21,27 @@ func (m *mockAdapter) Fetch(ctx context.Context) (Token, error) {
 	return args.Get(0).(Token), args.Error(1)
 }
 
+type mockClosingAdapter struct {
+	mockAdapter
+}
+
+func (m *mockClosingAdapter) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+type mockChangeDetectingAdapter struct {
+	mockAdapter
+}
+
+func (m *mockChangeDetectingAdapter) Changed(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.Error(1)
+}
+
 func TestNew(t *testing.T) {
 	a := new(mockAdapter)
+	u := new(mockUnmarshaler)
 	type args struct {
 		adapter Adapter
 		opts    []Option
@@ -33,6 +51,7 @@ func TestNew(t *testing.T) {
 		args        args
 		wantConfig  config
 		wantAdapter Adapter
+		wantToken   Token
 	}{
 		{
 			name: "New returns new fetcher with default values",
@@ -50,12 +69,60 @@ func TestNew(t *testing.T) {
 				adapter: a,
 				opts: []Option{
 					WithTokenExpiryBuffer(time.Hour),
+					WithMaxTokenAge(24 * time.Hour),
+					WithChangeDetection(time.Minute),
+					WithInitialToken(Token{AccessToken: "token-123"}),
+					WithCircuitBreaker(5, time.Minute),
+					WithClockSkew(time.Second),
+					WithMinRefreshInterval(time.Second),
+					WithRefreshAheadProbability(0.1),
+					WithDisableCache(),
+					WithRejectExpiredTokens(),
+					WithName("service-a"),
+					WithIdleEviction(time.Hour),
+					WithExpiryBufferFraction(0.2),
+					WithMaxResponseSize(1024),
+					WithJSONUnmarshaler(u),
+					WithMinUsableLifetime(10 * time.Minute),
+					WithAsyncRefresh(),
+					WithSecretCacheReuseOnParseError(),
+					WithStartupBackoff(time.Minute, time.Second),
+					WithRefreshOnCreatedAtChange(time.Hour),
+					WithStrictExpiryBuffer(),
+					WithClockJumpDetection(time.Minute),
+					WithSecretJSONPath("credentials.api"),
+					WithBackoff(NewConstantBackoff(time.Second)),
 				},
 			},
 			wantConfig: config{
-				tokenExpiryBuffer: time.Hour,
+				name:                 "service-a",
+				idleEviction:         time.Hour,
+				expiryBufferFraction: 0.2,
+				maxResponseSize:      1024,
+				minUsableLifetime:    10 * time.Minute,
+				startupWindow:        time.Minute,
+				startupDelay:         time.Second,
+				backoff:              constantBackoff{delay: time.Second},
+				decoder:              secretDecoder{unmarshaler: u, path: []string{"credentials", "api"}},
+				tokenExpiryBuffer:    time.Hour,
+				maxTokenAge:          24 * time.Hour,
+				changeCheckInterval:  time.Minute,
+				createdAtInterval:    time.Hour,
+				initialToken:         Token{AccessToken: "token-123"},
+				circuitFailures:      5,
+				circuitCooldown:      time.Minute,
+				clockSkew:            time.Second,
+				clockJumpThreshold:   time.Minute,
+				minRefreshInterval:   time.Second,
+				refreshAheadFraction: 0.1,
+				disableCache:         true,
+				asyncRefresh:         true,
+				rejectExpiredTokens:  true,
+				strictExpiryBuffer:   true,
+				reuseOnParseError:    true,
 			},
 			wantAdapter: a,
+			wantToken:   Token{AccessToken: "token-123"},
 		},
 	}
 	for _, tt := range tests {
@@ -63,6 +130,7 @@ func TestNew(t *testing.T) {
 			got := New(tt.args.adapter, tt.args.opts...)
 			assert.Equalf(t, tt.wantConfig, got.config, "New(%v, %v)", tt.args.adapter, tt.args.opts)
 			assert.Equalf(t, tt.wantAdapter, got.adapter, "New(%v, %v)", tt.args.adapter, tt.args.opts)
+			assert.Equalf(t, tt.wantToken, got.token, "New(%v, %v)", tt.args.adapter, tt.args.opts)
 
 			// Assert default values not overwritten
 			wantDefaultConfig := config{
@@ -82,30 +150,43 @@ func TestFetcher_Fetch(t *testing.T) {
 		token  Token
 	}
 	type args struct {
-		ctx context.Context
+		ctx  context.Context
+		opts []FetchOption
 	}
 	type mockOpts struct {
 		adapter func(m *mockAdapter)
 	}
 	tests := []struct {
-		name     string
-		fields   fields
-		args     args
-		mockOpts mockOpts
-		want     Token
-		wantErr  assert.ErrorAssertionFunc
+		name             string
+		fields           fields
+		args             args
+		mockOpts         mockOpts
+		want             Token
+		wantOnServeCalls []time.Duration
+		wantErr          assert.ErrorAssertionFunc
 	}{
 		{
 			name:    "valid token, returns token",
 			fields:  fields{config: defaultConfig, token: tok},
-			args:    args{context.Background()},
+			args:    args{ctx: context.Background()},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "valid token, change detected at source, returns new token",
+			fields: fields{config: config{changeCheckInterval: time.Minute}, token: Token{AccessToken: "old-token-123"}},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Changed", mock.Anything).Return(true, nil).Once()
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
 			want:    tok,
 			wantErr: assert.NoError,
 		},
 		{
 			name:   "missing token, returns new token",
 			fields: fields{config: defaultConfig},
-			args:   args{context.Background()},
+			args:   args{ctx: context.Background()},
 			mockOpts: mockOpts{func(m *mockAdapter) {
 				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
 			}},
@@ -115,18 +196,86 @@ func TestFetcher_Fetch(t *testing.T) {
 		{
 			name:   "missing token, adapter returns error, returns error",
 			fields: fields{config: defaultConfig},
-			args:   args{context.Background()},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name:             "valid token with expiry, returns token and calls on serve with remaining validity",
+			fields:           fields{config: defaultConfig, token: Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}},
+			args:             args{ctx: context.Background()},
+			want:             Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			wantOnServeCalls: []time.Duration{time.Hour},
+			wantErr:          assert.NoError,
+		},
+		{
+			name:   "missing token, returns new token with expiry and calls on serve with remaining validity",
+			fields: fields{config: defaultConfig},
+			args:   args{ctx: context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(2 * time.Hour)}, nil).Once()
+			}},
+			want:             Token{AccessToken: "token-123", Expiry: now.Add(2 * time.Hour)},
+			wantOnServeCalls: []time.Duration{2 * time.Hour},
+			wantErr:          assert.NoError,
+		},
+		{
+			name:             "token expired within clock skew, returns token and calls on serve with zero remaining validity",
+			fields:           fields{config: config{clockSkew: 5 * time.Minute}, token: Token{AccessToken: "token-123", Expiry: now.Add(-time.Minute)}},
+			args:             args{ctx: context.Background()},
+			want:             Token{AccessToken: "token-123", Expiry: now.Add(-time.Minute)},
+			wantOnServeCalls: []time.Duration{0},
+			wantErr:          assert.NoError,
+		},
+		{
+			name:   "valid token, force refresh, returns new token",
+			fields: fields{config: defaultConfig, token: Token{AccessToken: "old-token-123"}},
+			args:   args{ctx: context.Background(), opts: []FetchOption{ForceRefresh(), FetchTimeout(time.Minute)}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "reuse on parse error set, adapter returns malformed secret error with expired token cached, force refresh, returns error",
+			fields: fields{config: config{reuseOnParseError: true}, token: Token{AccessToken: "old-token-123", Expiry: now.Add(-time.Second)}},
+			args:   args{ctx: context.Background(), opts: []FetchOption{ForceRefresh()}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, fmt.Errorf("%w: error", ErrMalformedSecret)).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
+		{
+			name:   "reuse on parse error set, adapter returns other error with valid token cached, force refresh, returns error",
+			fields: fields{config: config{reuseOnParseError: true}, token: Token{AccessToken: "old-token-123", Expiry: now.Add(time.Minute)}},
+			args:   args{ctx: context.Background(), opts: []FetchOption{ForceRefresh()}},
 			mockOpts: mockOpts{func(m *mockAdapter) {
 				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
 			}},
 			wantErr: assert.Error,
 		},
+		{
+			name:   "reuse on parse error not set, adapter returns malformed secret error with valid token cached, force refresh, returns error",
+			fields: fields{token: Token{AccessToken: "old-token-123", Expiry: now.Add(time.Minute)}},
+			args:   args{ctx: context.Background(), opts: []FetchOption{ForceRefresh()}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, fmt.Errorf("%w: error", ErrMalformedSecret)).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mAdapter := new(mockAdapter)
+			mAdapter := new(mockChangeDetectingAdapter)
 			if tt.mockOpts.adapter != nil {
-				tt.mockOpts.adapter(mAdapter)
+				tt.mockOpts.adapter(&mAdapter.mockAdapter)
 			}
 
 			f := &Fetcher{
@@ -135,7 +284,13 @@ func TestFetcher_Fetch(t *testing.T) {
 				adapter: mAdapter,
 				token:   tt.fields.token,
 			}
-			got, err := f.Fetch(tt.args.ctx)
+			var gotOnServeCalls []time.Duration
+			f.config.onServe = func(remainingValidity time.Duration) {
+				gotOnServeCalls = append(gotOnServeCalls, remainingValidity)
+			}
+			got, err := f.Fetch(tt.args.ctx, tt.args.opts...)
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantOnServeCalls, gotOnServeCalls, "Fetch(%v)", tt.args.ctx)
 			if !tt.wantErr(t, err, fmt.Sprintf("Fetch(%v)", tt.args.ctx)) {
 				return
 			}
@@ -144,134 +299,274 @@ func TestFetcher_Fetch(t *testing.T) {
 	}
 }
 
-func TestFetcher_refreshRequired(t *testing.T) {
+func TestFetcher_FetchWithMeta(t *testing.T) {
 	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
-	past := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
-	future := time.Date(2030, 1, 2, 1, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+	timed := Token{AccessToken: "token-123", CreatedAt: now.Add(-10 * time.Minute), Expiry: now.Add(time.Hour)}
+	future := Token{AccessToken: "token-123", CreatedAt: now.Add(time.Minute), Expiry: now.Add(time.Hour)}
 
 	type fields struct {
-		config config
-		clock  clock.Clock
 		token  Token
+		closed bool
+	}
+	type args struct {
+		ctx context.Context
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
 	}
 	tests := []struct {
-		name   string
-		fields fields
-		want   bool
+		name     string
+		fields   fields
+		args     args
+		mockOpts mockOpts
+		want     Token
+		wantMeta FetchMeta
+		wantErr  assert.ErrorAssertionFunc
 	}{
 		{
-			name: "empty token, returns true",
-			fields: fields{
-				config: config{tokenExpiryBuffer: time.Minute},
-				clock:  clock.NewFixed(now),
-				token:  Token{},
-			},
-			want: true,
+			name:     "valid token, returns cached token and meta",
+			fields:   fields{token: tok},
+			want:     tok,
+			wantMeta: FetchMeta{Refreshed: false},
+			wantErr:  assert.NoError,
 		},
 		{
-			name: "token exists, expiry set in the past, returns true",
-			fields: fields{
-				config: config{tokenExpiryBuffer: time.Minute},
-				clock:  clock.NewFixed(now),
-				token:  Token{AccessToken: "token-123", Expiry: past},
-			},
-			want: true,
+			name: "missing token, returns refreshed token and meta",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:     tok,
+			wantMeta: FetchMeta{Refreshed: true},
+			wantErr:  assert.NoError,
 		},
 		{
-			name: "token exists, expiry set as now with expiry buffer set, returns true",
-			fields: fields{
-				config: config{tokenExpiryBuffer: time.Minute},
-				clock:  clock.NewFixed(now),
-				token:  Token{AccessToken: "token-123", Expiry: now},
-			},
-			want: true,
+			name: "missing token, adapter returns error, returns error and meta",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantMeta: FetchMeta{Refreshed: true},
+			wantErr:  assert.Error,
 		},
 		{
-			name: "token exists, expiry set as now with no expiry buffer set, returns false",
-			fields: fields{
-				config: config{tokenExpiryBuffer: 0},
-				clock:  clock.NewFixed(now),
-				token:  Token{AccessToken: "token-123", Expiry: now},
-			},
-			want: false,
+			name:     "valid token with timestamps, returns age and remaining validity",
+			fields:   fields{token: timed},
+			want:     timed,
+			wantMeta: FetchMeta{Age: 10 * time.Minute, RemainingValidity: time.Hour},
+			wantErr:  assert.NoError,
 		},
 		{
-			name: "token exists, expiry set in the future, returns false",
-			fields: fields{
-				config: config{tokenExpiryBuffer: time.Minute},
-				clock:  clock.NewFixed(now),
-				token:  Token{AccessToken: "token-123", Expiry: future},
-			},
-			want: false,
+			name: "refreshed token created in the future, returns zero age",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(future, nil).Once()
+			}},
+			want:     future,
+			wantMeta: FetchMeta{Refreshed: true, RemainingValidity: time.Hour},
+			wantErr:  assert.NoError,
 		},
 		{
-			name: "token exists, no expiry set, returns false",
-			fields: fields{
-				config: config{tokenExpiryBuffer: time.Minute},
-				clock:  clock.NewFixed(now),
-				token:  Token{AccessToken: "token-123"},
-			},
-			want: false,
+			name:     "token with timestamps in context, returns age and remaining validity",
+			args:     args{ContextWithToken(context.Background(), timed)},
+			want:     timed,
+			wantMeta: FetchMeta{Age: 10 * time.Minute, RemainingValidity: time.Hour},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "token in context, returns context token without fetching",
+			fields:   fields{token: tok},
+			args:     args{ContextWithToken(context.Background(), Token{AccessToken: "context-token-123"})},
+			want:     Token{AccessToken: "context-token-123"},
+			wantMeta: FetchMeta{},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "fetcher closed, returns error",
+			fields:   fields{closed: true},
+			wantMeta: FetchMeta{},
+			wantErr:  assert.Error,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
 			f := &Fetcher{
-				config: tt.fields.config,
-				clock:  clock.NewFixed(now),
-				token:  tt.fields.token,
+				config:  defaultConfig,
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+				token:   tt.fields.token,
+				closed:  tt.fields.closed,
+			}
+			ctx := tt.args.ctx
+			if ctx == nil {
+				ctx = context.Background()
 			}
-			assert.Equalf(t, tt.want, f.refreshRequired(), "refreshRequired()")
+			got, gotMeta, err := f.FetchWithMeta(ctx)
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantMeta, gotMeta, "FetchWithMeta()")
+			if !tt.wantErr(t, err, "FetchWithMeta()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "FetchWithMeta()")
 		})
 	}
 }
 
-func TestFetcher_refresh(t *testing.T) {
+func TestFetcher_fetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
 	tok := Token{AccessToken: "token-123"}
+	buffered := Token{AccessToken: "old-token-123", Expiry: now.Add(30 * time.Second)}
+	expired := Token{AccessToken: "old-token-123", Expiry: now.Add(-time.Second)}
+	circuitOpen := config{tokenExpiryBuffer: time.Minute, circuitFailures: 1, circuitCooldown: time.Minute}
+	throttled := config{tokenExpiryBuffer: time.Minute, minRefreshInterval: time.Minute}
+	refreshErr := errors.New("error")
 
 	type fields struct {
-		token Token
+		config              config
+		token               Token
+		refreshes           uint64
+		lastRefreshErr      error
+		lastRefreshFailedAt time.Time
+		circuitOpenUntil    time.Time
 	}
 	type args struct {
-		ctx context.Context
+		refreshes uint64
+		opts      fetchOptions
 	}
 	type mockOpts struct {
 		adapter func(m *mockAdapter)
 	}
 	tests := []struct {
-		name     string
-		fields   fields
-		args     args
-		mockOpts mockOpts
-		want     Token
-		wantErr  assert.ErrorAssertionFunc
+		name          string
+		fields        fields
+		args          args
+		mockOpts      mockOpts
+		want          Token
+		wantRefreshed bool
+		wantRefreshes uint64
+		wantHits      uint64
+		wantErr       assert.ErrorAssertionFunc
 	}{
 		{
-			name: "adapter returns token, returns token",
-			args: args{context.Background()},
+			name:          "cache enabled, valid token, returns cached token",
+			fields:        fields{config: defaultConfig, token: Token{AccessToken: "old-token-123"}},
+			want:          Token{AccessToken: "old-token-123"},
+			wantRefreshed: false,
+			wantHits:      1,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "cache disabled, valid token, returns refreshed token",
+			fields: fields{config: config{disableCache: true}, token: Token{AccessToken: "old-token-123"}},
 			mockOpts: mockOpts{func(m *mockAdapter) {
 				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
 			}},
-			want:    tok,
-			wantErr: assert.NoError,
+			want:          tok,
+			wantRefreshed: true,
+			wantRefreshes: 1,
+			wantErr:       assert.NoError,
 		},
 		{
-			name:   "adapter returns token, cached token already exists, returns token and cached token overwritten",
-			fields: fields{token: Token{AccessToken: "another-token-123"}},
-			args:   args{context.Background()},
+			name:          "cache disabled, refresh completed while waiting, returns refreshed token without calling adapter",
+			fields:        fields{config: config{disableCache: true}, token: tok, refreshes: 3},
+			args:          args{refreshes: 2},
+			want:          tok,
+			wantRefreshed: false,
+			wantRefreshes: 3,
+			wantHits:      1,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:          "cache disabled, refresh failed while waiting, returns refresh error without calling adapter",
+			fields:        fields{config: config{disableCache: true}, refreshes: 3, lastRefreshErr: errors.New("error")},
+			args:          args{refreshes: 2},
+			wantRefreshed: false,
+			wantRefreshes: 3,
+			wantErr:       assert.Error,
+		},
+		{
+			name:   "force refresh, valid token, returns refreshed token",
+			fields: fields{config: defaultConfig, token: Token{AccessToken: "old-token-123"}},
+			args:   args{opts: fetchOptions{forceRefresh: true}},
 			mockOpts: mockOpts{func(m *mockAdapter) {
 				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
 			}},
-			want:    tok,
-			wantErr: assert.NoError,
+			want:          tok,
+			wantRefreshed: true,
+			wantRefreshes: 1,
+			wantErr:       assert.NoError,
 		},
 		{
-			name: "adapter returns error, returns error",
-			args: args{context.Background()},
+			name:   "force refresh, cache disabled, refresh completed while waiting, returns refreshed token",
+			fields: fields{config: config{disableCache: true}, token: Token{AccessToken: "old-token-123"}, refreshes: 3},
+			args:   args{refreshes: 2, opts: fetchOptions{forceRefresh: true}},
 			mockOpts: mockOpts{func(m *mockAdapter) {
-				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
 			}},
-			wantErr: assert.Error,
+			want:          tok,
+			wantRefreshed: true,
+			wantRefreshes: 4,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "timeout set, missing token, refreshes token with timeout",
+			fields: fields{config: defaultConfig},
+			args:   args{opts: fetchOptions{timeout: time.Minute}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.MatchedBy(func(ctx context.Context) bool {
+					_, ok := ctx.Deadline()
+					return ok
+				})).Return(tok, nil).Once()
+			}},
+			want:          tok,
+			wantRefreshed: true,
+			wantRefreshes: 1,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:          "circuit open, token within expiry buffer, returns cached token as cache hit",
+			fields:        fields{config: circuitOpen, token: buffered, circuitOpenUntil: now.Add(time.Minute)},
+			want:          buffered,
+			wantRefreshed: false,
+			wantHits:      1,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:          "circuit open, token expired, returns circuit open error",
+			fields:        fields{config: circuitOpen, token: expired, circuitOpenUntil: now.Add(time.Minute)},
+			wantRefreshed: true,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrCircuitOpen, i...)
+			},
+		},
+		{
+			name:          "circuit open, force refresh, token within expiry buffer, returns circuit open error",
+			fields:        fields{config: circuitOpen, token: buffered, circuitOpenUntil: now.Add(time.Minute)},
+			args:          args{opts: fetchOptions{forceRefresh: true}},
+			wantRefreshed: true,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrCircuitOpen, i...)
+			},
+		},
+		{
+			name:          "min refresh interval set, refresh failed within interval, token within expiry buffer, returns cached token as cache hit",
+			fields:        fields{config: throttled, token: buffered, lastRefreshErr: refreshErr, lastRefreshFailedAt: now.Add(-time.Second)},
+			want:          buffered,
+			wantRefreshed: false,
+			wantHits:      1,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:          "min refresh interval set, refresh failed within interval, force refresh, returns last error",
+			fields:        fields{config: throttled, token: buffered, lastRefreshErr: refreshErr, lastRefreshFailedAt: now.Add(-time.Second)},
+			args:          args{opts: fetchOptions{forceRefresh: true}},
+			wantRefreshed: true,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, refreshErr, i...)
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -282,103 +577,1296 @@ func TestFetcher_refresh(t *testing.T) {
 			}
 
 			f := &Fetcher{
-				adapter: mAdapter,
-				token:   tt.fields.token,
+				config:              tt.fields.config,
+				clock:               clock.NewFixed(now),
+				adapter:             mAdapter,
+				token:               tt.fields.token,
+				lastRefreshErr:      tt.fields.lastRefreshErr,
+				lastRefreshFailedAt: tt.fields.lastRefreshFailedAt,
+				circuitOpenUntil:    tt.fields.circuitOpenUntil,
 			}
-			got, err := f.refresh(tt.args.ctx)
-			if !tt.wantErr(t, err, fmt.Sprintf("refresh(%v)", tt.args.ctx)) {
+			f.refreshes.Store(tt.fields.refreshes)
+			got, gotRefreshed, err := f.fetch(context.Background(), tt.args.refreshes, tt.args.opts)
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantRefreshed, gotRefreshed, "fetch(%v)", tt.args.refreshes)
+			assert.Equalf(t, tt.wantRefreshes, f.refreshes.Load(), "fetch(%v)", tt.args.refreshes)
+			assert.Equalf(t, tt.wantHits, f.stats.hits.Load(), "fetch(%v) hits", tt.args.refreshes)
+			if !tt.wantErr(t, err, fmt.Sprintf("fetch(%v)", tt.args.refreshes)) {
 				return
 			}
-			assert.Equalf(t, tt.want, got, "refresh(%v)", tt.args.ctx)
-			assert.Equalf(t, tt.want, f.token, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.want, got, "fetch(%v)", tt.args.refreshes)
 		})
 	}
 }
 
-type mockAWSSecretsManagerClient struct {
-	mock.Mock
-}
-
-func (m *mockAWSSecretsManagerClient) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
-	args := m.Called(ctx, params, optFns)
-	return args.Get(0).(*secretsmanager.GetSecretValueOutput), args.Error(1)
-}
+func TestFetcher_Close(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
 
-func Test_awsSecretsManagerAdapter_Fetch(t *testing.T) {
-	type fields struct {
-		key string
-	}
-	type args struct {
-		ctx context.Context
-	}
 	type mockOpts struct {
-		client func(m *mockAWSSecretsManagerClient)
+		adapter func(m *mockClosingAdapter)
 	}
 	tests := []struct {
 		name     string
-		fields   fields
-		args     args
+		closing  bool
 		mockOpts mockOpts
-		want     Token
 		wantErr  assert.ErrorAssertionFunc
 	}{
 		{
-			name:   "secrets manager returns valid secret, returns token",
-			fields: fields{key: "secret-key"},
-			args:   args{ctx: context.Background()},
-			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
-				m.On("GetSecretValue", mock.Anything, mock.MatchedBy(func(in *secretsmanager.GetSecretValueInput) bool {
-					return *in.SecretId == "secret-key"
-				}), mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
-					SecretString: aws.String(`{"access_token":"token-123","token_type":"bearer","refresh_token":"refresh-123","expiry":"2030-01-02T00:00:00Z","created_at":"2025-01-02T00:00:00Z"}`),
-				}, nil).Once()
-			}},
-			want: Token{
-				AccessToken:  "token-123",
-				TokenType:    "bearer",
-				RefreshToken: "refresh-123",
-				Expiry:       time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
-				CreatedAt:    time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
-			},
+			name:    "adapter not closer, returns nil",
 			wantErr: assert.NoError,
 		},
 		{
-			name:   "secrets manager returns invalid secret, returns error",
-			fields: fields{key: "secret-key"},
-			args:   args{ctx: context.Background()},
-			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
-				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{
-					SecretString: aws.String(`{invalid-json]`),
-				}, nil).Once()
+			name:    "adapter closer, closes adapter once and returns nil",
+			closing: true,
+			mockOpts: mockOpts{func(m *mockClosingAdapter) {
+				m.On("Close").Return(nil).Once()
 			}},
-			wantErr: assert.Error,
+			wantErr: assert.NoError,
 		},
 		{
-			name:   "secrets manager returns error, returns error",
-			fields: fields{key: "secret-key"},
-			args:   args{ctx: context.Background()},
-			mockOpts: mockOpts{func(m *mockAWSSecretsManagerClient) {
-				m.On("GetSecretValue", mock.Anything, mock.Anything, mock.Anything).Return(&secretsmanager.GetSecretValueOutput{}, errors.New("secrets manager error")).Once()
+			name:    "adapter closer returns error, returns error",
+			closing: true,
+			mockOpts: mockOpts{func(m *mockClosingAdapter) {
+				m.On("Close").Return(errors.New("error")).Once()
 			}},
 			wantErr: assert.Error,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mClient := new(mockAWSSecretsManagerClient)
-			if tt.mockOpts.client != nil {
-				tt.mockOpts.client(mClient)
+			mAdapter := new(mockClosingAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
 			}
 
-			a := awsSecretsManagerAdapter{
-				client: mClient,
-				key:    tt.fields.key,
+			f := &Fetcher{config: defaultConfig, clock: clock.NewFixed(now), token: tok}
+			f.adapter = &mAdapter.mockAdapter
+			if tt.closing {
+				f.adapter = mAdapter
 			}
-			got, err := a.Fetch(tt.args.ctx)
-			if !tt.wantErr(t, err, fmt.Sprintf("Fetch(%v)", tt.args.ctx)) {
-				return
+
+			tt.wantErr(t, f.Close(), "Close()")
+			assert.NoErrorf(t, f.Close(), "Close() second call")
+			assert.Equalf(t, Token{}, f.token, "Close()")
+
+			_, err := f.Fetch(context.Background())
+			assert.ErrorIsf(t, err, ErrFetcherClosed, "Fetch() after Close()")
+			mAdapter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetcher_Invalidate(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("token cached, clears token", func(t *testing.T) {
+		f := &Fetcher{token: Token{AccessToken: "token-123"}, fetchedAt: now}
+		f.Invalidate()
+		assert.Equalf(t, Token{}, f.token, "Invalidate() token")
+		assert.Equalf(t, time.Time{}, f.fetchedAt, "Invalidate() fetchedAt")
+	})
+}
+
+func TestFetcher_InvalidateIf(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Scopes: []string{"read"}}
+
+	tests := []struct {
+		name          string
+		token         Token
+		args          Token
+		want          bool
+		wantToken     Token
+		wantFetchedAt time.Time
+	}{
+		{
+			name:      "cached token matches, clears token and returns true",
+			token:     tok,
+			args:      Token{AccessToken: "token-123", Scopes: []string{"read"}},
+			want:      true,
+			wantToken: Token{},
+		},
+		{
+			name:      "cached token matches with different expiry, clears token and returns true",
+			token:     Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			args:      Token{AccessToken: "token-123", Expiry: now},
+			want:      true,
+			wantToken: Token{},
+		},
+		{
+			name:          "cached token with rotated refresh token, keeps token and returns false",
+			token:         Token{AccessToken: "token-123", RefreshToken: "refresh-456"},
+			args:          Token{AccessToken: "token-123", RefreshToken: "refresh-123"},
+			want:          false,
+			wantToken:     Token{AccessToken: "token-123", RefreshToken: "refresh-456"},
+			wantFetchedAt: now,
+		},
+		{
+			name:          "cached token replaced by refresh, keeps token and returns false",
+			token:         Token{AccessToken: "token-456"},
+			args:          tok,
+			want:          false,
+			wantToken:     Token{AccessToken: "token-456"},
+			wantFetchedAt: now,
+		},
+		{
+			name:      "no token cached, returns false",
+			token:     Token{},
+			args:      Token{},
+			want:      false,
+			wantToken: Token{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{token: tt.token}
+			if tt.token.AccessToken != "" {
+				f.fetchedAt = now
 			}
-			assert.Equalf(t, tt.want, got, "Fetch(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.want, f.InvalidateIf(tt.args), "InvalidateIf(%v)", tt.args)
+			assert.Equalf(t, tt.wantToken, f.token, "InvalidateIf(%v) token", tt.args)
+			assert.Equalf(t, tt.wantFetchedAt, f.fetchedAt, "InvalidateIf(%v) fetchedAt", tt.args)
+		})
+	}
+}
+
+func TestFetcher_Reset(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+	initialTok := Token{AccessToken: "initial-token-123"}
+
+	type fields struct {
+		config config
+		closed bool
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		wantToken     Token
+		wantFetchedAt time.Time
+		wantClosed    bool
+	}{
+		{
+			name:   "no initial token, clears token and state",
+			fields: fields{config: config{circuitFailures: 1}},
+		},
+		{
+			name:          "initial token set, restores initial token and clears state",
+			fields:        fields{config: config{circuitFailures: 1, initialToken: initialTok}},
+			wantToken:     initialTok,
+			wantFetchedAt: now,
+		},
+		{
+			name:       "closed fetcher, clears token and state and remains closed",
+			fields:     fields{config: config{circuitFailures: 1}, closed: true},
+			wantClosed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{
+				config:              tt.fields.config,
+				clock:               clock.NewFixed(now),
+				adapter:             new(mockAdapter),
+				token:               tok,
+				fetchedAt:           now.Add(-time.Minute),
+				lastChangeCheck:     now.Add(-time.Minute),
+				closed:              tt.fields.closed,
+				consecutiveFailures: 3,
+				circuitOpenUntil:    now.Add(time.Minute),
+				lastRefreshErr:      errors.New("error"),
+				lastRefreshFailedAt: now.Add(-time.Minute),
+				started:             true,
+				startupDeadline:     now.Add(time.Minute),
+			}
+			f.Reset()
+			assert.Equalf(t, tt.wantToken, f.token, "Reset() token")
+			assert.Equalf(t, tt.wantFetchedAt, f.fetchedAt, "Reset() fetchedAt")
+			assert.Equalf(t, time.Time{}, f.lastChangeCheck, "Reset() lastChangeCheck")
+			assert.Equalf(t, tt.wantClosed, f.closed, "Reset() closed")
+			assert.Equalf(t, 0, f.consecutiveFailures, "Reset() consecutiveFailures")
+			assert.Falsef(t, f.circuitOpen(), "Reset() circuitOpen")
+			assert.NoErrorf(t, f.lastRefreshErr, "Reset() lastRefreshErr")
+			assert.Equalf(t, time.Time{}, f.lastRefreshFailedAt, "Reset() lastRefreshFailedAt")
+			assert.Falsef(t, f.started, "Reset() started")
+			assert.Equalf(t, time.Time{}, f.startupDeadline, "Reset() startupDeadline")
+			assert.Equalf(t, tt.fields.config, f.config, "Reset() config")
+		})
+	}
+}
+
+func TestFetcher_Reset_stats(t *testing.T) {
+	mAdapter := new(mockAdapter)
+	mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+	mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+	f := New(mAdapter)
+
+	_, err := f.Fetch(context.Background())
+	assert.Errorf(t, err, "Fetch()")
+	_, err = f.Fetch(context.Background())
+	assert.NoErrorf(t, err, "Fetch()")
+	_, err = f.Fetch(context.Background())
+	assert.NoErrorf(t, err, "Fetch()")
+	stats := f.Stats()
+	assert.Equalf(t, FetcherStats{Hits: 1, Refreshes: 2, Failures: 1}, FetcherStats{Hits: stats.Hits, Refreshes: stats.Refreshes, Failures: stats.Failures}, "Stats()")
+
+	f.Reset()
+	assert.Equalf(t, FetcherStats{}, f.Stats(), "Reset() Stats()")
+	mAdapter.AssertExpectations(t)
+}
+
+func Test_config_refreshAfter(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		config    config
+		token     Token
+		fetchedAt time.Time
+		want      time.Time
+	}{
+		{
+			name:   "token without expiry, no max token age, returns zero time",
+			config: config{tokenExpiryBuffer: time.Minute},
+			token:  Token{AccessToken: "token-123"},
+			want:   time.Time{},
+		},
+		{
+			name:   "token with expiry, returns expiry less buffer",
+			config: config{tokenExpiryBuffer: time.Minute},
+			token:  Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			want:   now.Add(59 * time.Minute),
+		},
+		{
+			name:   "token with expiry, clock skew set, returns expiry less buffer plus skew",
+			config: config{tokenExpiryBuffer: time.Minute, clockSkew: 5 * time.Minute},
+			token:  Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			want:   now.Add(64 * time.Minute),
+		},
+		{
+			name:   "token with expiry, min usable lifetime beyond buffer, returns expiry less min usable lifetime",
+			config: config{tokenExpiryBuffer: time.Minute, minUsableLifetime: 10 * time.Minute},
+			token:  Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			want:   now.Add(50 * time.Minute),
+		},
+		{
+			name:   "token with expiry and created date, max token age before expiry, returns created date plus max token age",
+			config: config{tokenExpiryBuffer: time.Minute, maxTokenAge: 30 * time.Minute},
+			token:  Token{AccessToken: "token-123", Expiry: now.Add(time.Hour), CreatedAt: now},
+			want:   now.Add(30 * time.Minute),
+		},
+		{
+			name:      "token without created date, max token age set, returns fetched date plus max token age",
+			config:    config{tokenExpiryBuffer: time.Minute, maxTokenAge: 30 * time.Minute},
+			token:     Token{AccessToken: "token-123"},
+			fetchedAt: now,
+			want:      now.Add(30 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, tt.config.refreshAfter(tt.token, tt.fetchedAt), "refreshAfter(%v, %v)", tt.token, tt.fetchedAt)
+		})
+	}
+}
+
+func TestFetcher_refreshRequired_cached(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFixed(now)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+
+	f := &Fetcher{config: config{tokenExpiryBuffer: time.Minute}, clock: clk, token: tok}
+	required, _ := f.refreshRequired()
+	assert.Falsef(t, required, "refreshRequired()")
+	assert.Equalf(t, now.Add(59*time.Minute), f.refreshAfter, "refreshRequired() refresh after")
+
+	f.setToken(Token{AccessToken: "token-456", Expiry: now.Add(30 * time.Second)}, now)
+	required, _ = f.refreshRequired()
+	assert.Truef(t, required, "refreshRequired() after setToken()")
+	assert.Equalf(t, now.Add(-30*time.Second), f.refreshAfter, "refreshRequired() refresh after")
+}
+
+func TestFetcher_SetTokenExpiryBuffer(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(30 * time.Minute)}
+
+	t.Run("buffer updated, subsequent fetch uses updated buffer", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Once()
+
+		f := &Fetcher{
+			config:  config{tokenExpiryBuffer: time.Minute},
+			clock:   clock.NewFixed(now),
+			adapter: mAdapter,
+			token:   tok,
+		}
+		required, _ := f.refreshRequired()
+		assert.Falsef(t, required, "refreshRequired()")
+
+		f.SetTokenExpiryBuffer(time.Hour)
+		assert.Equalf(t, time.Hour, f.config.tokenExpiryBuffer, "SetTokenExpiryBuffer(%v)", time.Hour)
+		required, _ = f.refreshRequired()
+		assert.Truef(t, required, "refreshRequired()")
+
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("buffer updated concurrently with fetches, no data race", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil)
+
+		f := &Fetcher{
+			config:  config{tokenExpiryBuffer: time.Minute},
+			clock:   clock.NewFixed(now),
+			adapter: mAdapter,
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, _ = f.Fetch(context.Background())
+			}()
+			go func() {
+				defer wg.Done()
+				f.SetTokenExpiryBuffer(time.Duration(i) * time.Minute)
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestPrefetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+
+	type mockOpts struct {
+		adapters []func(m *mockAdapter)
+	}
+	tests := []struct {
+		name       string
+		mockOpts   mockOpts
+		wantTokens []Token
+		wantErrs   []error
+	}{
+		{
+			name:       "no fetchers, returns nil",
+			wantTokens: []Token{},
+		},
+		{
+			name: "all fetchers succeed, returns nil and caches tokens",
+			mockOpts: mockOpts{[]func(m *mockAdapter){
+				func(m *mockAdapter) { m.On("Fetch", mock.Anything).Return(tok, nil).Once() },
+				func(m *mockAdapter) { m.On("Fetch", mock.Anything).Return(tok, nil).Once() },
+			}},
+			wantTokens: []Token{tok, tok},
+		},
+		{
+			name: "some fetchers fail, returns joined errors",
+			mockOpts: mockOpts{[]func(m *mockAdapter){
+				func(m *mockAdapter) { m.On("Fetch", mock.Anything).Return(Token{}, err1).Once() },
+				func(m *mockAdapter) { m.On("Fetch", mock.Anything).Return(tok, nil).Once() },
+				func(m *mockAdapter) { m.On("Fetch", mock.Anything).Return(Token{}, err2).Once() },
+			}},
+			wantTokens: []Token{{}, tok, {}},
+			wantErrs:   []error{err1, err2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchers := make([]*Fetcher, len(tt.mockOpts.adapters))
+			for i, opt := range tt.mockOpts.adapters {
+				mAdapter := new(mockAdapter)
+				opt(mAdapter)
+				fetchers[i] = &Fetcher{config: defaultConfig, clock: clock.NewFixed(now), adapter: mAdapter}
+				defer mAdapter.AssertExpectations(t)
+			}
+
+			err := Prefetch(context.Background(), fetchers...)
+			if len(tt.wantErrs) == 0 {
+				assert.NoErrorf(t, err, "Prefetch()")
+			}
+			for _, wantErr := range tt.wantErrs {
+				assert.ErrorIsf(t, err, wantErr, "Prefetch()")
+			}
+
+			gotTokens := make([]Token, len(fetchers))
+			for i, f := range fetchers {
+				gotTokens[i] = f.token
+			}
+			assert.Equalf(t, tt.wantTokens, gotTokens, "Prefetch()")
+		})
+	}
+}
+
+func TestFetcher_Validate(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+
+	type fields struct {
+		token  Token
+		closed bool
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		mockOpts  mockOpts
+		wantErr   assert.ErrorAssertionFunc
+		wantToken Token
+	}{
+		{
+			name: "adapter returns token, returns nil without caching token",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "valid token cached, adapter returns token, returns nil and keeps cached token",
+			fields: fields{token: Token{AccessToken: "cached-token-123"}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			wantErr:   assert.NoError,
+			wantToken: Token{AccessToken: "cached-token-123"},
+		},
+		{
+			name: "adapter returns secret not found error, returns wrapped error",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, fmt.Errorf("%w secret-key", ErrSecretNotFound)).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrSecretNotFound, i...)
+			},
+		},
+		{
+			name: "adapter returns expired token, returns token expired error",
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)}, nil).Once()
+			}},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrTokenExpired, i...)
+			},
+		},
+		{
+			name:   "fetcher closed, returns error",
+			fields: fields{closed: true},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrFetcherClosed, i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &Fetcher{
+				config:  defaultConfig,
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+				token:   tt.fields.token,
+				closed:  tt.fields.closed,
+			}
+			err := f.Validate(context.Background())
+			mAdapter.AssertExpectations(t)
+			tt.wantErr(t, err, "Validate()")
+			assert.Equalf(t, tt.wantToken, f.token, "Validate()")
+		})
+	}
+}
+
+func TestFetcher_Healthy(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+
+	type fields struct {
+		config config
+		token  Token
+	}
+	type args struct {
+		ctx context.Context
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mockOpts  mockOpts
+		wantErr   assert.ErrorAssertionFunc
+		wantToken Token
+	}{
+		{
+			name:      "valid token cached, returns nil",
+			fields:    fields{config: defaultConfig, token: tok},
+			args:      args{context.Background()},
+			wantErr:   assert.NoError,
+			wantToken: tok,
+		},
+		{
+			name:   "missing token, adapter returns token, returns nil and caches token",
+			fields: fields{config: defaultConfig},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			wantErr:   assert.NoError,
+			wantToken: tok,
+		},
+		{
+			name:   "missing token, adapter returns error, returns error",
+			fields: fields{config: defaultConfig},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "missing token, adapter returns token expired within clock skew, returns nil",
+			fields: fields{config: config{clockSkew: 5 * time.Minute}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Minute)}, nil).Once()
+			}},
+			wantErr:   assert.NoError,
+			wantToken: Token{AccessToken: "token-123", Expiry: now.Add(-time.Minute)},
+		},
+		{
+			name:   "missing token, adapter returns expired token, returns error",
+			fields: fields{config: defaultConfig},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Hour)}, nil).Once()
+			}},
+			wantErr:   assert.Error,
+			wantToken: Token{AccessToken: "token-123", Expiry: now.Add(-time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &Fetcher{
+				config:  tt.fields.config,
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+				token:   tt.fields.token,
+			}
+			tt.wantErr(t, f.Healthy(tt.args.ctx), fmt.Sprintf("Healthy(%v)", tt.args.ctx))
+			assert.Equalf(t, tt.wantToken, f.token, "Healthy(%v)", tt.args.ctx)
+			mAdapter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetcher_named(t *testing.T) {
+	err := errors.New("error")
+
+	tests := []struct {
+		name     string
+		config   config
+		wantErr  string
+		wantName string
+	}{
+		{
+			name:    "no name set, returns error",
+			config:  config{},
+			wantErr: "error",
+		},
+		{
+			name:     "name set, returns error prefixed with name",
+			config:   config{name: "service-a"},
+			wantErr:  "service-a: error",
+			wantName: "service-a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{config: tt.config}
+			got := f.named(err)
+			assert.EqualErrorf(t, got, tt.wantErr, "named(%v)", err)
+			assert.ErrorIsf(t, got, err, "named(%v)", err)
+			assert.Equalf(t, tt.wantName, f.Name(), "Name()")
+		})
+	}
+}
+
+func TestFetcher_Adapter(t *testing.T) {
+	t.Run("Adapter returns adapter of fetcher", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		f := New(mAdapter)
+		assert.Samef(t, mAdapter, f.Adapter(), "Adapter()")
+	})
+}
+
+func TestFetcher_refreshRequired(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	past := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2030, 1, 2, 1, 0, 0, 0, time.UTC)
+
+	type fields struct {
+		config    config
+		clock     clock.Clock
+		token     Token
+		fetchedAt time.Time
+	}
+	tests := []struct {
+		name            string
+		fields          fields
+		want            bool
+		wantSynchronous bool
+	}{
+		{
+			name: "empty token, returns true and synchronous",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{},
+			},
+			want:            true,
+			wantSynchronous: true,
+		},
+		{
+			name: "token exists, expiry set in the past, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: past},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, expiry set as now with expiry buffer set, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: now},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, expiry set as now with no expiry buffer set, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: 0},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: now},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set in the future, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: future},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, no expiry set, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123"},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set beyond expiry buffer within min usable lifetime, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, minUsableLifetime: 2 * time.Hour},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: future},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, expiry set beyond min usable lifetime, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, minUsableLifetime: 30 * time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: future},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, no expiry set with min usable lifetime set, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, minUsableLifetime: 2 * time.Hour},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123"},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set beyond expiry buffer fraction of lifetime, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, expiryBufferFraction: 0.1},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", CreatedAt: now.Add(-50 * time.Minute), Expiry: now.Add(10 * time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set within expiry buffer fraction of lifetime, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, expiryBufferFraction: 0.1},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", CreatedAt: now.Add(-55 * time.Minute), Expiry: now.Add(5 * time.Minute)},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, no created date, expiry set within fixed expiry buffer with fraction set, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, expiryBufferFraction: 0.1},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(30 * time.Second)},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, expiry set in the past within clock skew, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, clockSkew: 5 * time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(-time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set in the past beyond clock skew, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, clockSkew: 5 * time.Minute},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(-5 * time.Minute)},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, no expiry set, created before max token age, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, maxTokenAge: time.Hour},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", CreatedAt: past},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, no expiry set, created within max token age, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, maxTokenAge: time.Hour},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", CreatedAt: now.Add(-time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, no created date, fetched before max token age, returns true",
+			fields: fields{
+				config:    config{tokenExpiryBuffer: time.Minute, maxTokenAge: time.Hour},
+				clock:     clock.NewFixed(now),
+				token:     Token{AccessToken: "token-123"},
+				fetchedAt: past,
+			},
+			want: true,
+		},
+		{
+			name: "token exists, no created date, fetched within max token age, returns false",
+			fields: fields{
+				config:    config{tokenExpiryBuffer: time.Minute, maxTokenAge: time.Hour},
+				clock:     clock.NewFixed(now),
+				token:     Token{AccessToken: "token-123"},
+				fetchedAt: now.Add(-time.Minute),
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set in the future, created before max token age, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, maxTokenAge: time.Hour},
+				clock:  clock.NewFixed(now),
+				token:  Token{AccessToken: "token-123", Expiry: future, CreatedAt: past},
+			},
+			want: true,
+		},
+		{
+			name: "token exists, expiry set in the future, refresh predicate returns true, returns true and synchronous",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshPredicate: func(current Token, now time.Time) bool {
+					return current.AccessToken == "token-123"
+				}},
+				clock: clock.NewFixed(now),
+				token: Token{AccessToken: "token-123", Expiry: future},
+			},
+			want:            true,
+			wantSynchronous: true,
+		},
+		{
+			name: "token exists, expiry set in the future, refresh predicate returns false, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshPredicate: func(current Token, now time.Time) bool {
+					return current.AccessToken != "token-123"
+				}},
+				clock: clock.NewFixed(now),
+				token: Token{AccessToken: "token-123", Expiry: future},
+			},
+			want: false,
+		},
+		{
+			name: "token exists, expiry set in the past, refresh predicate returns false, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshPredicate: func(current Token, now time.Time) bool {
+					return false
+				}},
+				clock: clock.NewFixed(now),
+				token: Token{AccessToken: "token-123", Expiry: past},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{
+				config:    tt.fields.config,
+				clock:     clock.NewFixed(now),
+				token:     tt.fields.token,
+				fetchedAt: tt.fields.fetchedAt,
+			}
+			got, gotSynchronous := f.refreshRequired()
+			assert.Equalf(t, tt.want, got, "refreshRequired()")
+			assert.Equalf(t, tt.wantSynchronous, gotSynchronous, "refreshRequired() synchronous")
+		})
+	}
+}
+
+func TestFetcher_sourceChanged(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	type fields struct {
+		config          config
+		lastChangeCheck time.Time
+	}
+	type mockOpts struct {
+		adapter func(m *mockChangeDetectingAdapter)
+	}
+	tests := []struct {
+		name                string
+		fields              fields
+		mockOpts            mockOpts
+		want                bool
+		wantLastChangeCheck time.Time
+	}{
+		{
+			name:   "change detection disabled, returns false",
+			fields: fields{config: config{}},
+			want:   false,
+		},
+		{
+			name:                "checked within interval, returns false",
+			fields:              fields{config: config{changeCheckInterval: time.Minute}, lastChangeCheck: now.Add(-time.Second)},
+			want:                false,
+			wantLastChangeCheck: now.Add(-time.Second),
+		},
+		{
+			name:   "check due, adapter reports change, returns true",
+			fields: fields{config: config{changeCheckInterval: time.Minute}, lastChangeCheck: now.Add(-time.Minute)},
+			mockOpts: mockOpts{func(m *mockChangeDetectingAdapter) {
+				m.On("Changed", mock.Anything).Return(true, nil).Once()
+			}},
+			want:                true,
+			wantLastChangeCheck: now,
+		},
+		{
+			name:   "check due, adapter reports no change, returns false",
+			fields: fields{config: config{changeCheckInterval: time.Minute}},
+			mockOpts: mockOpts{func(m *mockChangeDetectingAdapter) {
+				m.On("Changed", mock.Anything).Return(false, nil).Once()
+			}},
+			want:                false,
+			wantLastChangeCheck: now,
+		},
+		{
+			name:   "check due, adapter returns error, returns false",
+			fields: fields{config: config{changeCheckInterval: time.Minute}},
+			mockOpts: mockOpts{func(m *mockChangeDetectingAdapter) {
+				m.On("Changed", mock.Anything).Return(true, errors.New("error")).Once()
+			}},
+			want:                false,
+			wantLastChangeCheck: now,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockChangeDetectingAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &Fetcher{
+				config:          tt.fields.config,
+				clock:           clock.NewFixed(now),
+				adapter:         mAdapter,
+				lastChangeCheck: tt.fields.lastChangeCheck,
+			}
+			assert.Equalf(t, tt.want, f.sourceChanged(context.Background()), "sourceChanged()")
+			assert.Equalf(t, tt.wantLastChangeCheck, f.lastChangeCheck, "sourceChanged()")
+			mAdapter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetcher_refresh(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+
+	type fields struct {
+		config              config
+		token               Token
+		consecutiveFailures int
+		circuitOpenUntil    time.Time
+		lastRefreshErr      error
+		lastRefreshFailedAt time.Time
+	}
+	type args struct {
+		ctx context.Context
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name                    string
+		fields                  fields
+		args                    args
+		mockOpts                mockOpts
+		want                    Token
+		wantFetchedAt           time.Time
+		wantConsecutiveFailures int
+		wantCircuitOpenUntil    time.Time
+		wantOnRefreshErrorCalls []int
+		wantLastRefreshFailedAt time.Time
+		wantOnWarningCalls      int
+		wantOnWarningErr        error
+		wantErr                 assert.ErrorAssertionFunc
+	}{
+		{
+			name: "adapter returns token, returns token",
+			args: args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:          tok,
+			wantFetchedAt: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "adapter returns token, cached token already exists, returns token and cached token overwritten",
+			fields: fields{token: Token{AccessToken: "another-token-123"}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:          tok,
+			wantFetchedAt: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "adapter returns token after failures, returns token and resets consecutive failures",
+			fields: fields{consecutiveFailures: 3},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:          tok,
+			wantFetchedAt: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name: "adapter returns error, returns error and calls on refresh error",
+			args: args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantConsecutiveFailures: 1,
+			wantOnRefreshErrorCalls: []int{1},
+			wantLastRefreshFailedAt: now,
+			wantErr:                 assert.Error,
+		},
+		{
+			name:   "adapter returns error after failures, returns error and calls on refresh error with consecutive failures",
+			fields: fields{consecutiveFailures: 2},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantConsecutiveFailures: 3,
+			wantOnRefreshErrorCalls: []int{3},
+			wantLastRefreshFailedAt: now,
+			wantErr:                 assert.Error,
+		},
+		{
+			name:   "circuit breaker set, adapter returns error below failure threshold, returns error and circuit stays closed",
+			fields: fields{config: config{circuitFailures: 3, circuitCooldown: time.Minute}, consecutiveFailures: 1},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantConsecutiveFailures: 2,
+			wantOnRefreshErrorCalls: []int{2},
+			wantLastRefreshFailedAt: now,
+			wantErr:                 assert.Error,
+		},
+		{
+			name:   "circuit breaker set, adapter returns error reaching failure threshold, returns error and opens circuit",
+			fields: fields{config: config{circuitFailures: 3, circuitCooldown: time.Minute}, consecutiveFailures: 2},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantConsecutiveFailures: 3,
+			wantCircuitOpenUntil:    now.Add(time.Minute),
+			wantOnRefreshErrorCalls: []int{3},
+			wantLastRefreshFailedAt: now,
+			wantErr:                 assert.Error,
+		},
+		{
+			name:                    "circuit breaker open, returns circuit open error without calling adapter",
+			fields:                  fields{config: config{circuitFailures: 3, circuitCooldown: time.Minute}, consecutiveFailures: 3, circuitOpenUntil: now.Add(time.Second)},
+			args:                    args{context.Background()},
+			wantConsecutiveFailures: 3,
+			wantCircuitOpenUntil:    now.Add(time.Second),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrCircuitOpen, i...)
+			},
+		},
+		{
+			name:   "circuit breaker half open, adapter returns error, returns error and reopens circuit",
+			fields: fields{config: config{circuitFailures: 3, circuitCooldown: time.Minute}, consecutiveFailures: 3, circuitOpenUntil: now},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantConsecutiveFailures: 4,
+			wantCircuitOpenUntil:    now.Add(time.Minute),
+			wantOnRefreshErrorCalls: []int{4},
+			wantLastRefreshFailedAt: now,
+			wantErr:                 assert.Error,
+		},
+		{
+			name:   "circuit breaker half open, adapter returns token, returns token and closes circuit",
+			fields: fields{config: config{circuitFailures: 3, circuitCooldown: time.Minute}, consecutiveFailures: 3, circuitOpenUntil: now},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:                 tok,
+			wantFetchedAt:        now,
+			wantCircuitOpenUntil: now,
+			wantErr:              assert.NoError,
+		},
+		{
+			name:                    "min refresh interval set, refresh failed within interval, returns last error without calling adapter",
+			fields:                  fields{config: config{minRefreshInterval: time.Minute}, lastRefreshErr: errors.New("error"), lastRefreshFailedAt: now.Add(-time.Second)},
+			args:                    args{context.Background()},
+			wantLastRefreshFailedAt: now.Add(-time.Second),
+			wantErr:                 assert.Error,
+		},
+		{
+			name:   "min refresh interval set, refresh failed before interval, returns token",
+			fields: fields{config: config{minRefreshInterval: time.Minute}, lastRefreshErr: errors.New("error"), lastRefreshFailedAt: now.Add(-time.Minute)},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:                    tok,
+			wantFetchedAt:           now,
+			wantLastRefreshFailedAt: now.Add(-time.Minute),
+			wantErr:                 assert.NoError,
+		},
+		{
+			name: "adapter returns expired token, returns token and calls on warning",
+			args: args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)}, nil).Once()
+			}},
+			want:               Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)},
+			wantFetchedAt:      now,
+			wantOnWarningCalls: 1,
+			wantOnWarningErr:   ErrTokenExpired,
+			wantErr:            assert.NoError,
+		},
+		{
+			name:   "adapter returns token expired within clock skew, returns token",
+			fields: fields{config: config{clockSkew: time.Minute}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)},
+			wantFetchedAt: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "reject expired tokens set, adapter returns expired token, returns token expired error",
+			fields: fields{config: config{rejectExpiredTokens: true}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)}, nil).Once()
+			}},
+			wantConsecutiveFailures: 1,
+			wantOnRefreshErrorCalls: []int{1},
+			wantLastRefreshFailedAt: now,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrTokenExpired, i...)
+			},
+		},
+		{
+			name:   "adapter returns token with lifetime within expiry buffer, returns token and calls on warning",
+			fields: fields{config: config{tokenExpiryBuffer: time.Hour}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", CreatedAt: now, Expiry: now.Add(time.Hour)}, nil).Once()
+			}},
+			want:               Token{AccessToken: "token-123", CreatedAt: now, Expiry: now.Add(time.Hour)},
+			wantFetchedAt:      now,
+			wantOnWarningCalls: 1,
+			wantOnWarningErr:   ErrExpiryBufferExceedsLifetime,
+			wantErr:            assert.NoError,
+		},
+		{
+			name:   "adapter returns token with lifetime beyond expiry buffer, returns token",
+			fields: fields{config: config{tokenExpiryBuffer: time.Hour}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", CreatedAt: now, Expiry: now.Add(2 * time.Hour)}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", CreatedAt: now, Expiry: now.Add(2 * time.Hour)},
+			wantFetchedAt: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "min usable lifetime set, adapter returns token expiring within min usable lifetime, returns token and calls on warning",
+			fields: fields{config: config{minUsableLifetime: time.Hour}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(30 * time.Minute)}, nil).Once()
+			}},
+			want:               Token{AccessToken: "token-123", Expiry: now.Add(30 * time.Minute)},
+			wantFetchedAt:      now,
+			wantOnWarningCalls: 1,
+			wantOnWarningErr:   ErrBelowMinUsableLifetime,
+			wantErr:            assert.NoError,
+		},
+		{
+			name:   "min usable lifetime set, adapter returns token expiring beyond min usable lifetime, returns token",
+			fields: fields{config: config{minUsableLifetime: time.Hour}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(2 * time.Hour)}, nil).Once()
+			}},
+			want:          Token{AccessToken: "token-123", Expiry: now.Add(2 * time.Hour)},
+			wantFetchedAt: now,
+			wantErr:       assert.NoError,
+		},
+		{
+			name:   "min usable lifetime set, adapter returns expired token, returns token and calls on warning once",
+			fields: fields{config: config{minUsableLifetime: time.Hour}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)}, nil).Once()
+			}},
+			want:               Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)},
+			wantFetchedAt:      now,
+			wantOnWarningCalls: 1,
+			wantOnWarningErr:   ErrTokenExpired,
+			wantErr:            assert.NoError,
+		},
+		{
+			name:   "strict expiry buffer set, adapter returns token with lifetime within expiry buffer, returns expiry buffer error",
+			fields: fields{config: config{tokenExpiryBuffer: time.Hour, strictExpiryBuffer: true}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", CreatedAt: now, Expiry: now.Add(30 * time.Minute)}, nil).Once()
+			}},
+			wantConsecutiveFailures: 1,
+			wantOnRefreshErrorCalls: []int{1},
+			wantLastRefreshFailedAt: now,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrExpiryBufferExceedsLifetime, i...)
+			},
+		},
+		{
+			name:   "reuse on parse error set, adapter returns malformed secret error with valid token cached, returns cached token and calls on warning",
+			fields: fields{config: config{reuseOnParseError: true}, token: Token{AccessToken: "old-token-123", Expiry: now.Add(time.Minute)}},
+			args:   args{context.Background()},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, fmt.Errorf("%w: error", ErrMalformedSecret)).Once()
+			}},
+			want:                    Token{AccessToken: "old-token-123", Expiry: now.Add(time.Minute)},
+			wantConsecutiveFailures: 1,
+			wantOnRefreshErrorCalls: []int{1},
+			wantLastRefreshFailedAt: now,
+			wantOnWarningCalls:      1,
+			wantOnWarningErr:        ErrMalformedSecret,
+			wantErr:                 assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			var gotOnRefreshErrorCalls []int
+			f := &Fetcher{
+				config:              tt.fields.config,
+				clock:               clock.NewFixed(now),
+				adapter:             mAdapter,
+				token:               tt.fields.token,
+				consecutiveFailures: tt.fields.consecutiveFailures,
+				circuitOpenUntil:    tt.fields.circuitOpenUntil,
+				lastRefreshErr:      tt.fields.lastRefreshErr,
+				lastRefreshFailedAt: tt.fields.lastRefreshFailedAt,
+			}
+			f.config.onRefreshError = func(err error, consecutiveFailures int) {
+				gotOnRefreshErrorCalls = append(gotOnRefreshErrorCalls, consecutiveFailures)
+			}
+			var gotOnWarningCalls int
+			f.config.onWarning = func(err error) {
+				assert.ErrorIs(t, err, tt.wantOnWarningErr, "refresh(%v) warning", tt.args.ctx)
+				gotOnWarningCalls++
+			}
+			got, err := f.refresh(tt.args.ctx)
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantConsecutiveFailures, f.consecutiveFailures, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantCircuitOpenUntil, f.circuitOpenUntil, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantOnRefreshErrorCalls, gotOnRefreshErrorCalls, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantLastRefreshFailedAt, f.lastRefreshFailedAt, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantOnWarningCalls, gotOnWarningCalls, "refresh(%v)", tt.args.ctx)
+			if !tt.wantErr(t, err, fmt.Sprintf("refresh(%v)", tt.args.ctx)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.want, f.token, "refresh(%v)", tt.args.ctx)
+			assert.Equalf(t, tt.wantFetchedAt, f.fetchedAt, "refresh(%v)", tt.args.ctx)
 		})
 	}
 }
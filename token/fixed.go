@@ -0,0 +1,35 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"time"
+)
+
+// NewFixedFetcher returns a new Fetcher always returning the token rather than reading it from a backend, e.g. to use a
+// long-lived token injected by config in offline or air-gapped deployments. If the token has an expiry date, fetches
+// fail with ErrTokenExpired once it has passed.
+func NewFixedFetcher(t Token, opts ...Option) *Fetcher {
+	c := newConfig(opts...)
+	return newFetcher(fixedAdapter{
+		token:     t,
+		clock:     c.clock(),
+		clockSkew: c.clockSkew,
+	},
+		c,
+	)
+}
+
+type fixedAdapter struct {
+	token     Token
+	clock     clock.Clock
+	clockSkew time.Duration
+}
+
+func (a fixedAdapter) Fetch(_ context.Context) (Token, error) {
+	if a.token.Expired(a.clock.Now().Add(-a.clockSkew)) {
+		return Token{}, fmt.Errorf("%w at %s", ErrTokenExpired, a.token.Expiry.Format(time.RFC3339))
+	}
+	return a.token.Clone(), nil
+}
@@ -0,0 +1,65 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewFixedFetcher(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		token   Token
+		opts    []Option
+		want    Token
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "no expiry set, returns token",
+			token:   Token{AccessToken: "token-123"},
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "expiry set in the future, returns token",
+			token:   Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			want:    Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "expiry set within expiry buffer, returns token",
+			token:   Token{AccessToken: "token-123", Expiry: now.Add(time.Second)},
+			want:    Token{AccessToken: "token-123", Expiry: now.Add(time.Second)},
+			wantErr: assert.NoError,
+		},
+		{
+			name:  "expiry set in the past, returns token expired error",
+			token: Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrTokenExpired, i...)
+			},
+		},
+		{
+			name:    "expiry set in the past within clock skew, returns token",
+			token:   Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)},
+			opts:    []Option{WithClockSkew(time.Minute)},
+			want:    Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)},
+			wantErr: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithNowFunc(clock.NewFixed(now).Now)}, tt.opts...)
+			got, err := NewFixedFetcher(tt.token, opts...).Fetch(context.Background())
+			if !tt.wantErr(t, err, fmt.Sprintf("Fetch() %v", tt.token)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch() %v", tt.token)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package token
+
+import (
+	"context"
+)
+
+// NewFuncAdapter returns an Adapter fetching the raw secret value with fetch and parsing it into a token, so a new
+// backend can be integrated by supplying only the logic to read the value. Options affecting how secret values are
+// parsed, e.g. WithStrictJSON, are applied.
+func NewFuncAdapter(fetch func(ctx context.Context) ([]byte, error), opts ...Option) Adapter {
+	return funcAdapter{
+		fetch:   fetch,
+		decoder: newConfig(opts...).decoder,
+	}
+}
+
+type funcAdapter struct {
+	fetch   func(ctx context.Context) ([]byte, error)
+	decoder secretDecoder
+}
+
+func (a funcAdapter) Fetch(ctx context.Context) (Token, error) {
+	raw, err := a.fetch(ctx)
+	if err != nil {
+		return Token{}, sourceError("fetch token", "func", err)
+	}
+
+	t, err := a.decoder.decode(raw)
+	if err != nil {
+		return Token{}, sourceError("parse token", "func", err)
+	}
+	return t, nil
+}
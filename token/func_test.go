@@ -0,0 +1,59 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_funcAdapter_Fetch(t *testing.T) {
+	tests := []struct {
+		name    string
+		fetch   func(ctx context.Context) ([]byte, error)
+		opts    []Option
+		want    Token
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name: "fetch returns valid secret, returns token",
+			fetch: func(ctx context.Context) ([]byte, error) {
+				return []byte(`{"access_token":"token-123","expiry":1893542400}`), nil
+			},
+			want:    Token{AccessToken: "token-123", Expiry: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "fetch returns invalid secret, returns error",
+			fetch: func(ctx context.Context) ([]byte, error) {
+				return []byte(`{invalid-json]`), nil
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "strict option set, fetch returns secret with unknown field, returns error",
+			fetch: func(ctx context.Context) ([]byte, error) {
+				return []byte(`{"access_token":"token-123","unknown":true}`), nil
+			},
+			opts:    []Option{WithStrictJSON()},
+			wantErr: assert.Error,
+		},
+		{
+			name: "fetch returns error, returns error",
+			fetch: func(ctx context.Context) ([]byte, error) {
+				return nil, errors.New("error")
+			},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFuncAdapter(tt.fetch, tt.opts...).Fetch(context.Background())
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultGCPMetadataIdentityURL is the URL of the GCP metadata server endpoint issuing identity tokens for the default
+// service account
+const DefaultGCPMetadataIdentityURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity"
+
+// NewGCPMetadataIDTokenFetcher returns a new Fetcher with the gcpMetadataIDTokenAdapter, fetching an identity token for
+// the audience from the GCP metadata server, available on Compute Engine, Cloud Run and other GCP runtimes. The expiry
+// and created dates of the token are read from its exp and iat claims.
+func NewGCPMetadataIDTokenFetcher(audience string, opts ...Option) *Fetcher {
+	c := newConfig(opts...)
+	return newFetcher(gcpMetadataIDTokenAdapter{
+		url:      DefaultGCPMetadataIdentityURL + "?audience=" + url.QueryEscape(audience),
+		audience: audience,
+		client:   http.DefaultClient,
+		maxSize:  c.maxResponseSize,
+		decoder:  c.decoder,
+	},
+		c,
+	)
+}
+
+type gcpMetadataIDTokenAdapter struct {
+	url      string
+	audience string
+	client   *http.Client
+	maxSize  int64
+	decoder  secretDecoder
+}
+
+func (a gcpMetadataIDTokenAdapter) Fetch(ctx context.Context) (Token, error) {
+	b, err := a.get(ctx)
+	if err != nil {
+		return Token{}, sourceError("fetch token", a.source(), err)
+	}
+
+	idToken := strings.TrimSpace(string(b))
+	if idToken == "" {
+		return Token{}, sourceError("fetch token", a.source(), ErrEmptySecret)
+	}
+
+	t := Token{AccessToken: idToken}
+	if err := parseJWTDates(&t, a.decoder.unmarshal); err != nil {
+		return Token{}, sourceError("parse token", a.source(), err)
+	}
+	return t, nil
+}
+
+func (a gcpMetadataIDTokenAdapter) get(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return readAll(resp.Body, a.maxSize)
+}
+
+func (a gcpMetadataIDTokenAdapter) source() string {
+	return "gcp-metadata[" + a.audience + "]"
+}
+
+// parseJWTDates sets the expiry and created dates of t from the exp and iat claims of its access token, a JWT, parsing
+// the claims with unmarshal. The signature isn't verified, as the token is only read to schedule refreshes.
+func parseJWTDates(t *Token, unmarshal func(data []byte, v any) error) error {
+	parts := strings.Split(t.AccessToken, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: token is not a JWT", ErrMalformedSecret)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedSecret, err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+		Iat int64 `json:"iat"`
+	}
+	if err := unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedSecret, err)
+	}
+	if claims.Exp == 0 {
+		return fmt.Errorf("%w: JWT has no exp claim", ErrMalformedSecret)
+	}
+
+	t.Expiry = time.Unix(claims.Exp, 0).UTC()
+	if claims.Iat != 0 {
+		t.CreatedAt = time.Unix(claims.Iat, 0).UTC()
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testJWT(payload string) string {
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestNewGCPMetadataIDTokenFetcher(t *testing.T) {
+	u := &mockUnmarshaler{}
+
+	tests := []struct {
+		name        string
+		audience    string
+		opts        []Option
+		wantAdapter Adapter
+	}{
+		{
+			name:     "audience provided, returns fetcher fetching identity token for audience",
+			audience: "https://service-a.example.com",
+			wantAdapter: gcpMetadataIDTokenAdapter{
+				url:      "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity?audience=https%3A%2F%2Fservice-a.example.com",
+				audience: "https://service-a.example.com",
+				client:   http.DefaultClient,
+			},
+		},
+		{
+			name:     "max response size set, returns fetcher reading at most max response size",
+			audience: "service-a",
+			opts:     []Option{WithMaxResponseSize(1024)},
+			wantAdapter: gcpMetadataIDTokenAdapter{
+				url:      "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity?audience=service-a",
+				audience: "service-a",
+				client:   http.DefaultClient,
+				maxSize:  1024,
+			},
+		},
+		{
+			name:     "JSON unmarshaler set, returns fetcher parsing JWT claims with unmarshaler",
+			audience: "service-a",
+			opts:     []Option{WithJSONUnmarshaler(u)},
+			wantAdapter: gcpMetadataIDTokenAdapter{
+				url:      "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity?audience=service-a",
+				audience: "service-a",
+				client:   http.DefaultClient,
+				decoder:  secretDecoder{unmarshaler: u},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewGCPMetadataIDTokenFetcher(tt.audience, tt.opts...)
+			assert.Equalf(t, tt.wantAdapter, got.adapter, "NewGCPMetadataIDTokenFetcher(%v)", tt.audience)
+		})
+	}
+}
+
+func Test_gcpMetadataIDTokenAdapter_Fetch(t *testing.T) {
+	iat := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	exp := iat.Add(time.Hour)
+	idToken := testJWT(fmt.Sprintf(`{"aud":"service-a","iat":%d,"exp":%d}`, iat.Unix(), exp.Unix()))
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		maxSize int64
+		want    Token
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "metadata server returns identity token, returns token with dates from claims",
+			status:  http.StatusOK,
+			body:    idToken + "\n",
+			want:    Token{AccessToken: idToken, Expiry: exp, CreatedAt: iat},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "metadata server returns identity token without iat claim, returns token with expiry",
+			status:  http.StatusOK,
+			body:    testJWT(fmt.Sprintf(`{"exp":%d}`, exp.Unix())),
+			want:    Token{AccessToken: testJWT(fmt.Sprintf(`{"exp":%d}`, exp.Unix())), Expiry: exp},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "metadata server returns identity token without exp claim, returns malformed secret error",
+			status: http.StatusOK,
+			body:   testJWT(`{"aud":"service-a"}`),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
+		{
+			name:   "metadata server returns value that isn't a JWT, returns malformed secret error",
+			status: http.StatusOK,
+			body:   "token-123",
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrMalformedSecret, i...)
+			},
+		},
+		{
+			name:   "metadata server returns empty body, returns empty secret error",
+			status: http.StatusOK,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrEmptySecret, i...)
+			},
+		},
+		{
+			name:    "metadata server response exceeds max size, returns error",
+			status:  http.StatusOK,
+			body:    idToken,
+			maxSize: 8,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrResponseTooLarge, i...)
+			},
+		},
+		{
+			name:    "metadata server returns error status, returns error",
+			status:  http.StatusNotFound,
+			body:    "not found",
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equalf(t, "Google", r.Header.Get("Metadata-Flavor"), "Fetch() Metadata-Flavor header")
+				assert.Equalf(t, "service-a", r.URL.Query().Get("audience"), "Fetch() audience")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			a := gcpMetadataIDTokenAdapter{
+				url:      srv.URL + "?audience=service-a",
+				audience: "service-a",
+				client:   srv.Client(),
+				maxSize:  tt.maxSize,
+			}
+			got, err := a.Fetch(context.Background())
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
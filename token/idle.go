@@ -0,0 +1,43 @@
+package token
+
+import (
+	"time"
+)
+
+// WithIdleEviction clears the cached token once no fetch has occurred for the given duration, so a rarely used token
+// isn't held in memory indefinitely. The next fetch after eviction fetches a fresh token.
+func WithIdleEviction(idle time.Duration) Option {
+	return func(c *config) { c.idleEviction = idle }
+}
+
+// touch records an access of the cached token, first evicting it if it has been idle for the idle eviction duration.
+// It must be called while the Fetcher is locked.
+func (f *Fetcher) touch() {
+	if f.config.idleEviction <= 0 {
+		return
+	}
+
+	f.evictIdle()
+	f.lastAccess = f.clock.Now()
+	if f.idleTimer == nil {
+		f.idleTimer = time.AfterFunc(f.config.idleEviction, f.evictIdleLocked)
+		return
+	}
+	f.idleTimer.Reset(f.config.idleEviction)
+}
+
+// evictIdleLocked evicts the cached token if idle, locking the Fetcher. It is called once the idle timer fires, so the
+// token is dropped even if the Fetcher is never used again.
+func (f *Fetcher) evictIdleLocked() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.evictIdle()
+}
+
+// evictIdle clears the cached token if it hasn't been accessed for the idle eviction duration
+func (f *Fetcher) evictIdle() {
+	if f.lastAccess.IsZero() || f.clock.Since(f.lastAccess) < f.config.idleEviction {
+		return
+	}
+	f.setToken(Token{}, time.Time{})
+}
@@ -0,0 +1,92 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestFetcher_touch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+
+	type fields struct {
+		config     config
+		lastAccess time.Time
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		wantToken      Token
+		wantLastAccess time.Time
+	}{
+		{
+			name:           "idle eviction not set, keeps token",
+			fields:         fields{lastAccess: now.Add(-time.Hour)},
+			wantToken:      tok,
+			wantLastAccess: now.Add(-time.Hour),
+		},
+		{
+			name:           "first access, keeps token and records access",
+			fields:         fields{config: config{idleEviction: time.Minute}},
+			wantToken:      tok,
+			wantLastAccess: now,
+		},
+		{
+			name:           "accessed within idle duration, keeps token and records access",
+			fields:         fields{config: config{idleEviction: time.Minute}, lastAccess: now.Add(-30 * time.Second)},
+			wantToken:      tok,
+			wantLastAccess: now,
+		},
+		{
+			name:           "idle for idle duration, evicts token and records access",
+			fields:         fields{config: config{idleEviction: time.Minute}, lastAccess: now.Add(-time.Minute)},
+			wantToken:      Token{},
+			wantLastAccess: now,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{
+				config:     tt.fields.config,
+				clock:      clock.NewFixed(now),
+				token:      tok,
+				fetchedAt:  now.Add(-time.Hour),
+				lastAccess: tt.fields.lastAccess,
+			}
+			f.touch()
+			if f.idleTimer != nil {
+				f.idleTimer.Stop()
+			}
+			assert.Equalf(t, tt.wantToken, f.token, "touch() token")
+			assert.Equalf(t, tt.wantLastAccess, f.lastAccess, "touch() lastAccess")
+		})
+	}
+}
+
+func TestWithIdleEviction(t *testing.T) {
+	tok := Token{AccessToken: "token-123"}
+
+	t.Run("no fetch within idle duration, evicts token and fetches on next use", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Twice()
+
+		f := New(mAdapter, WithIdleEviction(10*time.Millisecond))
+		defer func() { _ = f.Close() }()
+
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Eventuallyf(t, func() bool {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			return f.token.AccessToken == ""
+		}, time.Second, time.Millisecond, "Fetch() token evicted")
+
+		_, err = f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+}
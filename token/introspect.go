@@ -0,0 +1,112 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultIntrospectionInterval is the interval between introspections if WithIntrospectionInterval isn't set
+const defaultIntrospectionInterval = time.Minute
+
+// WithIntrospection introspects the cached token with the OAuth 2.0 token introspection endpoint (RFC 7662) at url,
+// refreshing the token if the endpoint reports it inactive, e.g. to detect revocation that the expiry date can't.
+// clientAuth authenticates each introspection request, e.g. with BasicClientAuth, and may be nil. The cached token is
+// introspected at most once per minute, see WithIntrospectionInterval. Introspection is best effort, so a failed
+// introspection leaves the cached token in place.
+func WithIntrospection(url string, clientAuth func(req *http.Request)) Option {
+	return func(c *config) {
+		c.introspection.url = url
+		c.introspection.clientAuth = clientAuth
+		c.introspection.client = http.DefaultClient
+	}
+}
+
+// WithIntrospectionInterval sets the minimum interval between introspections of the cached token set by
+// WithIntrospection
+func WithIntrospectionInterval(interval time.Duration) Option {
+	return func(c *config) { c.introspection.interval = interval }
+}
+
+// BasicClientAuth returns a function authenticating introspection requests with HTTP basic authentication, the usual
+// client authentication of introspection endpoints
+func BasicClientAuth(clientID, clientSecret string) func(req *http.Request) {
+	return func(req *http.Request) { req.SetBasicAuth(clientID, clientSecret) }
+}
+
+// introspection is the introspection endpoint set by WithIntrospection, disabled if url is empty
+type introspection struct {
+	url        string
+	clientAuth func(req *http.Request)
+	client     *http.Client
+	interval   time.Duration
+}
+
+// due reports whether the cached token, last introspected at last, should be introspected at now
+func (in introspection) due(last time.Time, now time.Time) bool {
+	interval := in.interval
+	if interval <= 0 {
+		interval = defaultIntrospectionInterval
+	}
+	return in.url != "" && !now.Before(last.Add(interval))
+}
+
+// introspect reports whether the introspection endpoint reports the access token active, parsing the response with the
+// Unmarshaler of the decoder
+func (in introspection) introspect(ctx context.Context, accessToken string, maxSize int64, d secretDecoder) (bool, error) {
+	form := url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if in.clientAuth != nil {
+		in.clientAuth(req)
+	}
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	b, err := readAll(resp.Body, maxSize)
+	if err != nil {
+		return false, err
+	}
+	var body struct {
+		Active bool `json:"active"`
+	}
+	if err := d.unmarshal(b, &body); err != nil {
+		return false, fmt.Errorf("unable to parse introspection response: %w", err)
+	}
+	return body.Active, nil
+}
+
+// revoked reports whether the introspection endpoint reports the cached token inactive, introspecting at most once per
+// interval. Introspection is best effort, so a failed introspection leaves the cached token in place.
+func (f *Fetcher) revoked(ctx context.Context) bool {
+	now := f.clock.Now()
+	if !f.config.introspection.due(f.introspectedAt, now) {
+		return false
+	}
+	f.introspectedAt = now
+
+	active, err := f.config.introspection.introspect(ctx, f.token.AccessToken, f.config.maxResponseSize, f.config.decoder)
+	if err != nil {
+		f.log(ctx, slog.LevelWarn, "token introspection failed", "err", err)
+		return false
+	}
+	if !active {
+		f.log(ctx, slog.LevelWarn, "token introspected inactive, refreshing")
+	}
+	return !active
+}
@@ -0,0 +1,208 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithIntrospection(t *testing.T) {
+	auth := BasicClientAuth("client-123", "secret-123")
+	c := newConfig(WithIntrospection("https://auth.example.com/introspect", auth), WithIntrospectionInterval(time.Hour))
+	assert.Equalf(t, "https://auth.example.com/introspect", c.introspection.url, "WithIntrospection()")
+	assert.NotNilf(t, c.introspection.clientAuth, "WithIntrospection()")
+	assert.Equalf(t, http.DefaultClient, c.introspection.client, "WithIntrospection()")
+	assert.Equalf(t, time.Hour, c.introspection.interval, "WithIntrospectionInterval()")
+}
+
+func Test_introspection_due(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		introspection introspection
+		last          time.Time
+		want          bool
+	}{
+		{
+			name: "introspection disabled, returns false",
+			want: false,
+		},
+		{
+			name:          "never introspected, returns true",
+			introspection: introspection{url: "https://auth.example.com/introspect"},
+			want:          true,
+		},
+		{
+			name:          "introspected within default interval, returns false",
+			introspection: introspection{url: "https://auth.example.com/introspect"},
+			last:          now.Add(-time.Second),
+			want:          false,
+		},
+		{
+			name:          "introspected default interval ago, returns true",
+			introspection: introspection{url: "https://auth.example.com/introspect"},
+			last:          now.Add(-time.Minute),
+			want:          true,
+		},
+		{
+			name:          "introspected within interval, returns false",
+			introspection: introspection{url: "https://auth.example.com/introspect", interval: time.Hour},
+			last:          now.Add(-time.Minute),
+			want:          false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, tt.introspection.due(tt.last, now), "due(%v, %v)", tt.last, now)
+		})
+	}
+}
+
+func Test_introspection_introspect(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		maxSize int64
+		want    bool
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "endpoint reports token active, returns true",
+			status:  http.StatusOK,
+			body:    `{"active":true,"scope":"read"}`,
+			want:    true,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "endpoint reports token inactive, returns false",
+			status:  http.StatusOK,
+			body:    `{"active":false}`,
+			want:    false,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "endpoint returns invalid response, returns error",
+			status:  http.StatusOK,
+			body:    `not json`,
+			wantErr: assert.Error,
+		},
+		{
+			name:    "endpoint response exceeds max size, returns error",
+			status:  http.StatusOK,
+			body:    `{"active":true}`,
+			maxSize: 8,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrResponseTooLarge, i...)
+			},
+		},
+		{
+			name:    "endpoint returns error status, returns error",
+			status:  http.StatusUnauthorized,
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equalf(t, http.MethodPost, r.Method, "introspect() method")
+				assert.Equalf(t, "token-123", r.FormValue("token"), "introspect() token")
+				assert.Equalf(t, "access_token", r.FormValue("token_type_hint"), "introspect() token_type_hint")
+				clientID, clientSecret, _ := r.BasicAuth()
+				assert.Equalf(t, "client-123", clientID, "introspect() client ID")
+				assert.Equalf(t, "secret-123", clientSecret, "introspect() client secret")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			in := introspection{url: srv.URL, clientAuth: BasicClientAuth("client-123", "secret-123"), client: srv.Client()}
+			got, err := in.introspect(context.Background(), "token-123", tt.maxSize, secretDecoder{})
+			if !tt.wantErr(t, err, "introspect()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "introspect()")
+		})
+	}
+}
+
+func TestFetcher_revoked(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+	newTok := Token{AccessToken: "token-456", Expiry: now.Add(time.Hour)}
+
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		introspectedAt time.Time
+		want           Token
+		wantRefresh    bool
+		wantCalls      int
+	}{
+		{
+			name:      "introspection due, token active, returns cached token",
+			status:    http.StatusOK,
+			body:      `{"active":true}`,
+			want:      tok,
+			wantCalls: 1,
+		},
+		{
+			name:        "introspection due, token inactive, returns refreshed token",
+			status:      http.StatusOK,
+			body:        `{"active":false}`,
+			want:        newTok,
+			wantRefresh: true,
+			wantCalls:   1,
+		},
+		{
+			name:      "introspection due, introspection fails, returns cached token",
+			status:    http.StatusInternalServerError,
+			want:      tok,
+			wantCalls: 1,
+		},
+		{
+			name:           "introspected within interval, returns cached token without introspecting",
+			introspectedAt: now.Add(-time.Second),
+			want:           tok,
+			wantCalls:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			mAdapter := new(mockAdapter)
+			if tt.wantRefresh {
+				mAdapter.On("Fetch", mock.Anything).Return(newTok, nil).Once()
+			}
+			f := &Fetcher{
+				config:         defaultConfig,
+				clock:          clock.NewFixed(now),
+				adapter:        mAdapter,
+				token:          tok,
+				fetchedAt:      now.Add(-time.Hour),
+				introspectedAt: tt.introspectedAt,
+			}
+			f.config.introspection = introspection{url: srv.URL, client: srv.Client()}
+
+			got, err := f.Fetch(context.Background())
+			assert.NoErrorf(t, err, "Fetch()")
+			assert.Equalf(t, tt.want, got, "Fetch()")
+			assert.Equalf(t, tt.wantCalls, calls, "Fetch() introspections")
+			mAdapter.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package token
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// DefaultKubernetesSATokenPath is the path of the service account token projected into pods by Kubernetes
+const DefaultKubernetesSATokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// NewKubernetesSATokenFetcher returns a new Fetcher with the kubernetesSATokenAdapter, reading the projected service
+// account token at path, or DefaultKubernetesSATokenPath if path is empty. The token file is re-read on each refresh
+// to pick up rotation by the kubelet; as it carries no expiry, use WithMaxTokenAge to control how often.
+func NewKubernetesSATokenFetcher(path string, opts ...Option) *Fetcher {
+	if path == "" {
+		path = DefaultKubernetesSATokenPath
+	}
+	c := newConfig(opts...)
+	return newFetcher(kubernetesSATokenAdapter{
+		path:    path,
+		maxSize: c.maxResponseSize,
+	},
+		c,
+	)
+}
+
+type kubernetesSATokenAdapter struct {
+	path    string
+	maxSize int64
+}
+
+func (a kubernetesSATokenAdapter) Fetch(_ context.Context) (Token, error) {
+	b, err := a.read()
+	if err != nil {
+		return Token{}, sourceError("read token", "file["+a.path+"]", err)
+	}
+
+	accessToken := strings.TrimSpace(string(b))
+	if accessToken == "" {
+		return Token{}, sourceError("read token", "file["+a.path+"]", ErrEmptySecret)
+	}
+
+	return Token{AccessToken: accessToken}, nil
+}
+
+func (a kubernetesSATokenAdapter) read() ([]byte, error) {
+	f, err := os.Open(a.path) // #nosec G304 -- path is provided by the caller
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return readAll(f, a.maxSize)
+}
@@ -0,0 +1,103 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewKubernetesSATokenFetcher(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		opts        []Option
+		wantAdapter Adapter
+	}{
+		{
+			name:        "empty path, returns fetcher reading default path",
+			wantAdapter: kubernetesSATokenAdapter{path: "/var/run/secrets/kubernetes.io/serviceaccount/token"},
+		},
+		{
+			name:        "path provided, returns fetcher reading provided path",
+			path:        "/tmp/token",
+			wantAdapter: kubernetesSATokenAdapter{path: "/tmp/token"},
+		},
+		{
+			name:        "max response size set, returns fetcher reading at most max response size",
+			path:        "/tmp/token",
+			opts:        []Option{WithMaxResponseSize(1024)},
+			wantAdapter: kubernetesSATokenAdapter{path: "/tmp/token", maxSize: 1024},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewKubernetesSATokenFetcher(tt.path, tt.opts...)
+			assert.Equalf(t, tt.wantAdapter, got.adapter, "NewKubernetesSATokenFetcher(%v)", tt.path)
+		})
+	}
+}
+
+func Test_kubernetesSATokenAdapter_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		maxSize int64
+		want    Token
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "file contains token, returns token",
+			path:    writeFile("token", "token-123"),
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "file contains token with trailing newline, returns trimmed token",
+			path:    writeFile("token-newline", "token-123\n"),
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "file empty, returns empty secret error",
+			path: writeFile("token-empty", " \n"),
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrEmptySecret, i...)
+			},
+		},
+		{
+			name:    "file exceeds max size, returns error",
+			path:    writeFile("token-large", "token-123"),
+			maxSize: 8,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrResponseTooLarge, i...)
+			},
+		},
+		{
+			name:    "file missing, returns error",
+			path:    filepath.Join(dir, "missing"),
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := kubernetesSATokenAdapter{path: tt.path, maxSize: tt.maxSize}
+			got, err := a.Fetch(context.Background())
+			if !tt.wantErr(t, err, fmt.Sprintf("Fetch() %v", tt.path)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch() %v", tt.path)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package token
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger sets the logger refreshes are logged with. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) { c.logger = logger }
+}
+
+// WithContextLogger sets a function returning the logger refreshes are logged with from the context of the fetch, e.g.
+// a request-scoped logger carrying a trace ID, so refresh logs can be correlated with the triggering request. The
+// logger set by WithLogger is used if the function returns nil.
+func WithContextLogger(fn func(ctx context.Context) *slog.Logger) Option {
+	return func(c *config) { c.contextLogger = fn }
+}
+
+// log logs a message with the logger for the context, if any, attributed with the name of the Fetcher
+func (f *Fetcher) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	var logger *slog.Logger
+	if f.config.contextLogger != nil {
+		logger = f.config.contextLogger(ctx)
+	}
+	if logger == nil {
+		logger = f.config.logger
+	}
+	if logger == nil {
+		return
+	}
+	if f.config.name != "" {
+		logger = logger.With("fetcher", f.config.name)
+	}
+	logger.Log(ctx, level, msg, args...)
+}
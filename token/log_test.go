@@ -0,0 +1,104 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestFetcher_log(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace-123")
+
+	newLogger := func(buf *bytes.Buffer) *slog.Logger {
+		return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+	}
+
+	tests := []struct {
+		name           string
+		fetcher        string
+		withLogger     bool
+		withCtxLogger  bool
+		ctxLoggerNil   bool
+		wantStatic     string
+		wantContextual string
+	}{
+		{
+			name: "no logger set, logs nothing",
+		},
+		{
+			name:       "logger set, logs with logger",
+			withLogger: true,
+			wantStatic: "level=WARN msg=message key=value\n",
+		},
+		{
+			name:           "context logger set, logs with context logger",
+			withLogger:     true,
+			withCtxLogger:  true,
+			wantContextual: "level=WARN msg=message trace=trace-123 key=value\n",
+		},
+		{
+			name:          "context logger returns nil, logs with logger",
+			withLogger:    true,
+			withCtxLogger: true,
+			ctxLoggerNil:  true,
+			wantStatic:    "level=WARN msg=message key=value\n",
+		},
+		{
+			name:       "fetcher named, logs with fetcher name",
+			fetcher:    "service-a",
+			withLogger: true,
+			wantStatic: "level=WARN msg=message fetcher=service-a key=value\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var static, contextual bytes.Buffer
+			f := &Fetcher{config: config{name: tt.fetcher}}
+			if tt.withLogger {
+				f.config.logger = newLogger(&static)
+			}
+			if tt.withCtxLogger {
+				f.config.contextLogger = func(ctx context.Context) *slog.Logger {
+					if tt.ctxLoggerNil {
+						return nil
+					}
+					return newLogger(&contextual).With("trace", ctx.Value(ctxKey{}))
+				}
+			}
+			f.log(ctx, slog.LevelWarn, "message", "key", "value")
+			assert.Equalf(t, tt.wantStatic, static.String(), "log() logger")
+			assert.Equalf(t, tt.wantContextual, contextual.String(), "log() context logger")
+		})
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("refresh fails, logs failure", func(t *testing.T) {
+		var buf bytes.Buffer
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+
+		f := New(mAdapter, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+		f.clock = clock.NewFixed(now)
+		_, err := f.Fetch(context.Background())
+		assert.Errorf(t, err, "Fetch()")
+		assert.Containsf(t, buf.String(), `msg="token refresh failed" err=error consecutive_failures=1`, "Fetch() log")
+		mAdapter.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,163 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MultiFetcher fetches access tokens from a source holding several tokens keyed by name, e.g. a single secret with
+// credentials for several services. All tokens are refreshed together with a single adapter call when the requested
+// token requires a refresh.
+//
+// The expiry buffer and max token age options apply to each token individually.
+type MultiFetcher struct {
+	mu        sync.Mutex
+	config    config
+	clock     clock.Clock
+	adapter   MultiAdapter
+	tokens    map[string]Token
+	fetchedAt time.Time
+}
+
+// NewMulti returns a new MultiFetcher with the provided MultiAdapter
+func NewMulti(adapter MultiAdapter, opts ...Option) *MultiFetcher {
+	return newMultiFetcher(adapter, newConfig(opts...))
+}
+
+func newMultiFetcher(adapter MultiAdapter, c config) *MultiFetcher {
+	return &MultiFetcher{
+		config:  c,
+		clock:   c.clock(),
+		adapter: adapter,
+	}
+}
+
+// FetchNamed returns the token with the given name, refreshing all tokens if it is missing or requires a refresh.
+// ErrTokenNotFound is returned if the source has no token with the name.
+func (f *MultiFetcher) FetchNamed(ctx context.Context, name string) (Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if t, ok := f.tokens[name]; ok && !f.config.refreshRequired(t, f.fetchedAt, f.clock.Now()) {
+		return t.Clone(), nil
+	}
+
+	if err := f.refresh(ctx); err != nil {
+		return Token{}, err
+	}
+
+	t, ok := f.tokens[name]
+	if !ok {
+		return Token{}, fmt.Errorf("%w: %s", ErrTokenNotFound, name)
+	}
+	return t.Clone(), nil
+}
+
+// FetchScoped returns a token granted all the scopes, refreshing all tokens if no valid cached token is granted them.
+// Of the tokens granted the scopes, the token with the fewest scopes is returned, so a broader token isn't used where a
+// narrower one will do. ErrTokenNotFound is returned if the source has no token granted the
+// scopes.
+func (f *MultiFetcher) FetchScoped(ctx context.Context, scopes ...string) (Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if t, ok := f.scoped(scopes); ok {
+		return t.Clone(), nil
+	}
+
+	if err := f.refresh(ctx); err != nil {
+		return Token{}, err
+	}
+
+	t, ok := f.scoped(scopes)
+	if !ok {
+		return Token{}, fmt.Errorf("%w with scopes: %s", ErrTokenNotFound, strings.Join(scopes, " "))
+	}
+	return t.Clone(), nil
+}
+
+// FetchAll returns all tokens keyed by name, refreshing all tokens if none are cached or any requires a refresh, e.g.
+// to warm the cache at startup or to inspect every token. Tokens that are expired after refreshing are left out of the
+// map, with an error for each, wrapping ErrTokenExpired, joined into the returned error.
+func (f *MultiFetcher) FetchAll(ctx context.Context) (map[string]Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.tokens == nil || f.anyRefreshRequired() {
+		if err := f.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	now := f.clock.Now().Add(-f.config.clockSkew)
+	tokens := make(map[string]Token, len(f.tokens))
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(f.tokens)) {
+		t := f.tokens[name]
+		if t.Expired(now) {
+			errs = append(errs, fmt.Errorf("%s: %w at %s", name, ErrTokenExpired, t.Expiry.Format(time.RFC3339)))
+			continue
+		}
+		tokens[name] = t.Clone()
+	}
+	return tokens, errors.Join(errs...)
+}
+
+// anyRefreshRequired reports whether any cached token requires a refresh
+func (f *MultiFetcher) anyRefreshRequired() bool {
+	now := f.clock.Now()
+	for _, t := range f.tokens {
+		if f.config.refreshRequired(t, f.fetchedAt, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// scoped returns the cached token granted all the scopes with the fewest scopes, ignoring tokens requiring a refresh.
+// Ties are broken by name, so the same token is returned for the same scopes.
+func (f *MultiFetcher) scoped(scopes []string) (Token, bool) {
+	now := f.clock.Now()
+	var (
+		match Token
+		found bool
+	)
+	for _, name := range slices.Sorted(maps.Keys(f.tokens)) {
+		t := f.tokens[name]
+		if !t.HasScopes(scopes...) || f.config.refreshRequired(t, f.fetchedAt, now) {
+			continue
+		}
+		if !found || len(t.Scopes) < len(match.Scopes) {
+			match, found = t, true
+		}
+	}
+	return match, found
+}
+
+func (f *MultiFetcher) refresh(ctx context.Context) error {
+	tokens, err := f.adapter.FetchAll(ctx)
+	if err != nil {
+		return err
+	}
+	for name, t := range tokens {
+		if tokens[name], err = postProcess(f.config.postProcessor, t, nil); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	f.tokens = tokens
+	f.fetchedAt = f.clock.Now()
+	return nil
+}
+
+// MultiAdapter fetches a set of tokens keyed by name
+type MultiAdapter interface {
+	FetchAll(ctx context.Context) (map[string]Token, error)
+}
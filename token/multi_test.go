@@ -0,0 +1,327 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type mockMultiAdapter struct {
+	mock.Mock
+}
+
+func (m *mockMultiAdapter) FetchAll(ctx context.Context) (map[string]Token, error) {
+	args := m.Called(ctx)
+	tokens, _ := args.Get(0).(map[string]Token)
+	return tokens, args.Error(1)
+}
+
+func TestNewMulti(t *testing.T) {
+	a := new(mockMultiAdapter)
+
+	t.Run("NewMulti returns new multi fetcher with provided options", func(t *testing.T) {
+		got := NewMulti(a, WithTokenExpiryBuffer(time.Hour))
+		assert.Equalf(t, config{tokenExpiryBuffer: time.Hour}, got.config, "NewMulti()")
+		assert.Equalf(t, a, got.adapter, "NewMulti()")
+	})
+}
+
+func TestMultiFetcher_FetchNamed(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tokA := Token{AccessToken: "token-a"}
+	tokB := Token{AccessToken: "token-b", Expiry: now.Add(time.Hour)}
+	expiringTokA := Token{AccessToken: "old-token-a", Expiry: now.Add(time.Second)}
+
+	type fields struct {
+		tokens map[string]Token
+	}
+	type args struct {
+		name string
+	}
+	type mockOpts struct {
+		adapter func(m *mockMultiAdapter)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mockOpts   mockOpts
+		want       Token
+		wantTokens map[string]Token
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "valid token cached, returns cached token",
+			fields:     fields{tokens: map[string]Token{"a": tokA, "b": tokB}},
+			args:       args{"b"},
+			want:       tokB,
+			wantTokens: map[string]Token{"a": tokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "no tokens cached, returns fetched token and caches all tokens",
+			args: args{"a"},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": tokA, "b": tokB}, nil).Once()
+			}},
+			want:       tokA,
+			wantTokens: map[string]Token{"a": tokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "cached token requires refresh, returns fetched token and refreshes all tokens",
+			fields: fields{tokens: map[string]Token{"a": expiringTokA, "b": tokB}},
+			args:   args{"a"},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": tokA, "b": tokB}, nil).Once()
+			}},
+			want:       tokA,
+			wantTokens: map[string]Token{"a": tokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:       "other cached token requires refresh, returns cached token",
+			fields:     fields{tokens: map[string]Token{"a": expiringTokA, "b": tokB}},
+			args:       args{"b"},
+			want:       tokB,
+			wantTokens: map[string]Token{"a": expiringTokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "token missing from source, returns not found error",
+			args: args{"c"},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": tokA}, nil).Once()
+			}},
+			wantTokens: map[string]Token{"a": tokA},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrTokenNotFound, i...)
+			},
+		},
+		{
+			name:   "adapter returns error, returns error and keeps cached tokens",
+			fields: fields{tokens: map[string]Token{"a": expiringTokA}},
+			args:   args{"a"},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(nil, errors.New("error")).Once()
+			}},
+			wantTokens: map[string]Token{"a": expiringTokA},
+			wantErr:    assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockMultiAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &MultiFetcher{
+				config:  defaultConfig,
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+				tokens:  tt.fields.tokens,
+			}
+			got, err := f.FetchNamed(context.Background(), tt.args.name)
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantTokens, f.tokens, "FetchNamed(%v)", tt.args.name)
+			if !tt.wantErr(t, err, fmt.Sprintf("FetchNamed(%v)", tt.args.name)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "FetchNamed(%v)", tt.args.name)
+		})
+	}
+}
+
+func TestMultiFetcher_FetchAll(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tokA := Token{AccessToken: "token-a"}
+	tokB := Token{AccessToken: "token-b", Expiry: now.Add(time.Hour)}
+	expiringTokA := Token{AccessToken: "old-token-a", Expiry: now.Add(time.Second)}
+	expiredTokB := Token{AccessToken: "token-b", Expiry: now.Add(-time.Hour)}
+
+	type fields struct {
+		tokens map[string]Token
+	}
+	type mockOpts struct {
+		adapter func(m *mockMultiAdapter)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		mockOpts   mockOpts
+		want       map[string]Token
+		wantTokens map[string]Token
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "valid tokens cached, returns cached tokens",
+			fields:     fields{tokens: map[string]Token{"a": tokA, "b": tokB}},
+			want:       map[string]Token{"a": tokA, "b": tokB},
+			wantTokens: map[string]Token{"a": tokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "no tokens cached, returns fetched tokens and caches all tokens",
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": tokA, "b": tokB}, nil).Once()
+			}},
+			want:       map[string]Token{"a": tokA, "b": tokB},
+			wantTokens: map[string]Token{"a": tokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "cached token requires refresh, returns fetched tokens and refreshes all tokens",
+			fields: fields{tokens: map[string]Token{"a": expiringTokA, "b": tokB}},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": tokA, "b": tokB}, nil).Once()
+			}},
+			want:       map[string]Token{"a": tokA, "b": tokB},
+			wantTokens: map[string]Token{"a": tokA, "b": tokB},
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "fetched token expired, returns valid tokens and token expired error",
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": tokA, "b": expiredTokB}, nil).Once()
+			}},
+			want:       map[string]Token{"a": tokA},
+			wantTokens: map[string]Token{"a": tokA, "b": expiredTokB},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrTokenExpired, i...) && assert.ErrorContains(t, err, "b: token expired", i...)
+			},
+		},
+		{
+			name:   "adapter returns error, returns error and keeps cached tokens",
+			fields: fields{tokens: map[string]Token{"a": expiringTokA}},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(nil, errors.New("error")).Once()
+			}},
+			wantTokens: map[string]Token{"a": expiringTokA},
+			wantErr:    assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockMultiAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &MultiFetcher{
+				config:  defaultConfig,
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+				tokens:  tt.fields.tokens,
+			}
+			got, err := f.FetchAll(context.Background())
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantTokens, f.tokens, "FetchAll()")
+			assert.Equalf(t, tt.want, got, "FetchAll()")
+			tt.wantErr(t, err, "FetchAll()")
+		})
+	}
+}
+
+func TestMultiFetcher_FetchScoped(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	readTok := Token{AccessToken: "token-read", Scopes: []string{"read"}}
+	readWriteTok := Token{AccessToken: "token-read-write", Scopes: []string{"read", "write"}}
+	expiringReadTok := Token{AccessToken: "old-token-read", Expiry: now.Add(time.Second), Scopes: []string{"read"}}
+
+	type fields struct {
+		tokens map[string]Token
+	}
+	type args struct {
+		scopes []string
+	}
+	type mockOpts struct {
+		adapter func(m *mockMultiAdapter)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mockOpts   mockOpts
+		want       Token
+		wantTokens map[string]Token
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "tokens cached with scopes, returns cached token with fewest scopes",
+			fields:     fields{tokens: map[string]Token{"a": readWriteTok, "b": readTok}},
+			args:       args{[]string{"read"}},
+			want:       readTok,
+			wantTokens: map[string]Token{"a": readWriteTok, "b": readTok},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:       "token cached with broader scopes only, returns broader token",
+			fields:     fields{tokens: map[string]Token{"a": readWriteTok, "b": readTok}},
+			args:       args{[]string{"write"}},
+			want:       readWriteTok,
+			wantTokens: map[string]Token{"a": readWriteTok, "b": readTok},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "token with scopes requires refresh, refreshes tokens and returns new token",
+			fields: fields{tokens: map[string]Token{"a": expiringReadTok}},
+			args:   args{[]string{"read"}},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": readTok}, nil).Once()
+			}},
+			want:       readTok,
+			wantTokens: map[string]Token{"a": readTok},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "no token with scopes after refresh, returns token not found error",
+			fields: fields{tokens: map[string]Token{"a": readTok}},
+			args:   args{[]string{"admin"}},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(map[string]Token{"a": readTok}, nil).Once()
+			}},
+			wantTokens: map[string]Token{"a": readTok},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrTokenNotFound, i...)
+			},
+		},
+		{
+			name:   "adapter returns error, returns error and keeps cached tokens",
+			fields: fields{tokens: map[string]Token{"a": expiringReadTok}},
+			args:   args{[]string{"read"}},
+			mockOpts: mockOpts{func(m *mockMultiAdapter) {
+				m.On("FetchAll", mock.Anything).Return(nil, errors.New("error")).Once()
+			}},
+			wantTokens: map[string]Token{"a": expiringReadTok},
+			wantErr:    assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockMultiAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			f := &MultiFetcher{
+				config:  defaultConfig,
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+				tokens:  tt.fields.tokens,
+			}
+			got, err := f.FetchScoped(context.Background(), tt.args.scopes...)
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantTokens, f.tokens, "FetchScoped(%v)", tt.args.scopes)
+			if !tt.wantErr(t, err, fmt.Sprintf("FetchScoped(%v)", tt.args.scopes)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "FetchScoped(%v)", tt.args.scopes)
+		})
+	}
+}
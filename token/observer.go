@@ -0,0 +1,84 @@
+package token
+
+import "time"
+
+// FetchObserver observes the events of a Fetcher through a single type, as an alternative to the granular callback
+// options. Embed NoopFetchObserver to implement only the methods of interest. Its methods are invoked while the Fetcher
+// is locked, so must not call the Fetcher.
+type FetchObserver interface {
+	// OnCacheHit is invoked each time a fetch is served the cached token without waiting for a refresh
+	OnCacheHit(t Token)
+	// OnRefresh is invoked each time a refresh succeeds, with the latency of the adapter call
+	OnRefresh(t Token, latency time.Duration)
+	// OnRefreshError is invoked each time a refresh fails, as the callback set by WithOnRefreshError
+	OnRefreshError(err error, consecutiveFailures int)
+	// OnNewToken is invoked each time a refresh caches a token with a different credential to the previous one, as
+	// reported by Token.SameCredential
+	OnNewToken(t Token)
+	// OnServe is invoked each time a token is served, as the callback set by WithOnServe
+	OnServe(remainingValidity time.Duration)
+	// OnWarning is invoked with conditions that don't fail a fetch, as the callback set by WithOnWarning
+	OnWarning(err error)
+}
+
+// NoopFetchObserver implements FetchObserver with methods that do nothing, to be embedded by observers implementing
+// only some of its methods
+type NoopFetchObserver struct{}
+
+func (NoopFetchObserver) OnCacheHit(Token)               {}
+func (NoopFetchObserver) OnRefresh(Token, time.Duration) {}
+func (NoopFetchObserver) OnRefreshError(error, int)      {}
+func (NoopFetchObserver) OnNewToken(Token)               {}
+func (NoopFetchObserver) OnServe(time.Duration)          {}
+func (NoopFetchObserver) OnWarning(error)                {}
+
+// WithObserver sets an observer of the events of the Fetcher. Its methods are invoked after the callbacks set by
+// WithOnRefreshError, WithOnServe and WithOnWarning, if also set.
+func WithObserver(obs FetchObserver) Option {
+	return func(c *config) { c.observer = obs }
+}
+
+// observe chains the observer's methods after the callbacks of the config they correspond to, if an observer is set
+func (c config) observe() config {
+	obs := c.observer
+	if obs == nil {
+		return c
+	}
+
+	if fn := c.onRefreshError; fn != nil {
+		c.onRefreshError = func(err error, consecutiveFailures int) {
+			fn(err, consecutiveFailures)
+			obs.OnRefreshError(err, consecutiveFailures)
+		}
+	} else {
+		c.onRefreshError = obs.OnRefreshError
+	}
+	if fn := c.onServe; fn != nil {
+		c.onServe = func(remainingValidity time.Duration) {
+			fn(remainingValidity)
+			obs.OnServe(remainingValidity)
+		}
+	} else {
+		c.onServe = obs.OnServe
+	}
+	if fn := c.onWarning; fn != nil {
+		c.onWarning = func(err error) {
+			fn(err)
+			obs.OnWarning(err)
+		}
+	} else {
+		c.onWarning = obs.OnWarning
+	}
+	return c
+}
+
+// observe invokes fn with the observer, if set, recovering panics if panic recovery is enabled
+func (f *Fetcher) observe(fn func(obs FetchObserver)) {
+	if f.config.observer == nil {
+		return
+	}
+	if f.config.panicRecovery {
+		defer f.config.recoverPanic()
+	}
+	fn(f.config.observer)
+}
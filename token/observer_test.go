@@ -0,0 +1,125 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	NoopFetchObserver
+	events []string
+}
+
+func (o *recordingObserver) OnCacheHit(t Token) {
+	o.events = append(o.events, "cache hit "+t.AccessToken)
+}
+
+func (o *recordingObserver) OnRefresh(t Token, _ time.Duration) {
+	o.events = append(o.events, "refresh "+t.AccessToken)
+}
+
+func (o *recordingObserver) OnRefreshError(error, int) {
+	o.events = append(o.events, "refresh error")
+}
+
+func (o *recordingObserver) OnNewToken(t Token) {
+	o.events = append(o.events, "new token "+t.AccessToken)
+}
+
+func (o *recordingObserver) OnWarning(error) {
+	o.events = append(o.events, "warning")
+}
+
+type panickingObserver struct {
+	NoopFetchObserver
+}
+
+func (panickingObserver) OnCacheHit(Token)               { panic("observer") }
+func (panickingObserver) OnRefresh(Token, time.Duration) { panic("observer") }
+
+func TestWithObserver(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+
+	t.Run("observes refreshes, new tokens, cache hits and refresh errors", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Twice()
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+
+		obs := &recordingObserver{}
+		f := New(mAdapter, WithNowFunc(func() time.Time { return now }), WithObserver(obs))
+		ctx := context.Background()
+
+		_, err := f.Fetch(ctx)
+		assert.NoErrorf(t, err, "Fetch()")
+		_, err = f.Fetch(ctx)
+		assert.NoErrorf(t, err, "Fetch()")
+		_, err = f.Fetch(ctx, ForceRefresh())
+		assert.NoErrorf(t, err, "Fetch(ForceRefresh())")
+		_, err = f.Fetch(ctx, ForceRefresh())
+		assert.Errorf(t, err, "Fetch(ForceRefresh())")
+
+		want := []string{"refresh token-123", "new token token-123", "cache hit token-123", "refresh token-123", "refresh error"}
+		assert.Equalf(t, want, obs.events, "Fetch() observed events")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("refresh token rotated with same access token, observes new token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", RefreshToken: "refresh-1", Expiry: now.Add(time.Hour)}, nil).Once()
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", RefreshToken: "refresh-2", Expiry: now.Add(time.Hour)}, nil).Once()
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", RefreshToken: "refresh-2", Expiry: now.Add(2 * time.Hour)}, nil).Once()
+
+		obs := &recordingObserver{}
+		f := New(mAdapter, WithNowFunc(func() time.Time { return now }), WithObserver(obs))
+		for range 3 {
+			_, err := f.Fetch(context.Background(), ForceRefresh())
+			assert.NoErrorf(t, err, "Fetch(ForceRefresh())")
+		}
+
+		want := []string{"refresh token-123", "new token token-123", "refresh token-123", "new token token-123", "refresh token-123"}
+		assert.Equalf(t, want, obs.events, "Fetch() observed events")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("callbacks also set, invokes callbacks then observer", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Hour)}, nil).Once()
+
+		obs := &recordingObserver{}
+		f := New(mAdapter,
+			WithNowFunc(func() time.Time { return now }),
+			WithOnWarning(func(err error) { obs.events = append(obs.events, "callback warning") }),
+			WithObserver(obs),
+		)
+
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, []string{"callback warning", "warning", "refresh token-123", "new token token-123"}, obs.events, "Fetch() observed events")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("panic recovery enabled, recovers observer panics", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Once()
+
+		var recovered []any
+		f := New(mAdapter,
+			WithNowFunc(func() time.Time { return now }),
+			WithPanicRecovery(func(r any) { recovered = append(recovered, r) }),
+			WithObserver(panickingObserver{}),
+		)
+
+		for range 2 {
+			got, err := f.Fetch(context.Background())
+			assert.NoErrorf(t, err, "Fetch()")
+			assert.Equalf(t, tok, got, "Fetch()")
+		}
+		assert.Equalf(t, []any{"observer", "observer"}, recovered, "Fetch() recovered panics")
+		mAdapter.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,107 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// WithPanicRecovery recovers panics in the callbacks set by options, such as the refresh error, serve and warning
+// callbacks, the observer, the refresh predicate, the context logger, the raw response sink, the secret transformer and
+// the token post processor, so a buggy callback can't take down a fetch. Recovered panics are passed to fn, which may
+// be nil to discard them. A panicking secret transformer or token post processor fails the fetch with an error, a
+// panicking refresh predicate doesn't require a refresh, and a panicking context logger falls back to the logger set by
+// WithLogger.
+func WithPanicRecovery(fn func(recovered any)) Option {
+	return func(c *config) {
+		c.panicRecovery = true
+		c.onPanic = fn
+	}
+}
+
+// recoverPanics wraps the callbacks of the config to recover panics, if panic recovery is enabled
+func (c config) recoverPanics() config {
+	if !c.panicRecovery {
+		return c
+	}
+
+	if fn := c.onRefreshError; fn != nil {
+		c.onRefreshError = func(err error, consecutiveFailures int) {
+			defer c.recoverPanic()
+			fn(err, consecutiveFailures)
+		}
+	}
+	if fn := c.onServe; fn != nil {
+		c.onServe = func(remainingValidity time.Duration) {
+			defer c.recoverPanic()
+			fn(remainingValidity)
+		}
+	}
+	if fn := c.onWarning; fn != nil {
+		c.onWarning = func(err error) {
+			defer c.recoverPanic()
+			fn(err)
+		}
+	}
+	if fn := c.refreshPredicate; fn != nil {
+		c.refreshPredicate = func(current Token, now time.Time) bool {
+			defer c.recoverPanic()
+			return fn(current, now)
+		}
+	}
+	if fn := c.contextLogger; fn != nil {
+		c.contextLogger = func(ctx context.Context) *slog.Logger {
+			defer c.recoverPanic()
+			return fn(ctx)
+		}
+	}
+	if fn := c.clockDrift.fn; fn != nil {
+		c.clockDrift.fn = func(drift time.Duration) {
+			defer c.recoverPanic()
+			fn(drift)
+		}
+	}
+	if fn := c.decoder.rawSink; fn != nil {
+		c.decoder.rawSink = func(raw []byte) {
+			defer c.recoverPanic()
+			fn(raw)
+		}
+	}
+	if fn := c.decoder.transformer; fn != nil {
+		c.decoder.transformer = func(raw []byte) (transformed []byte, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					c.reportPanic(r)
+					err = fmt.Errorf("secret transformer panicked: %v", r)
+				}
+			}()
+			return fn(raw)
+		}
+	}
+	if fn := c.postProcessor; fn != nil {
+		c.postProcessor = func(t Token) (processed Token, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					c.reportPanic(r)
+					err = fmt.Errorf("token post processor panicked: %v", r)
+				}
+			}()
+			return fn(t)
+		}
+	}
+	return c
+}
+
+// recoverPanic recovers a panic and reports it, so must be deferred
+func (c config) recoverPanic() {
+	if r := recover(); r != nil {
+		c.reportPanic(r)
+	}
+}
+
+func (c config) reportPanic(recovered any) {
+	if c.onPanic != nil {
+		c.onPanic(recovered)
+	}
+}
@@ -0,0 +1,86 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestWithPanicRecovery(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	nowFunc := func() time.Time { return now }
+
+	t.Run("fetcher callbacks panic, recovers panics and reports them", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(-time.Second)}, nil).Once()
+
+		var got []any
+		f := New(mAdapter,
+			WithNowFunc(nowFunc),
+			WithPanicRecovery(func(recovered any) { got = append(got, recovered) }),
+			WithOnRefreshError(func(err error, consecutiveFailures int) { panic("on refresh error") }),
+			WithOnWarning(func(err error) { panic("on warning") }),
+			WithOnServe(func(remainingValidity time.Duration) { panic("on serve") }),
+		)
+
+		_, err := f.Fetch(context.Background())
+		assert.Errorf(t, err, "Fetch()")
+		_, err = f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, []any{"on refresh error", "on warning", "on serve"}, got, "WithPanicRecovery()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("decoder callbacks panic, recovers panics and fails transform", func(t *testing.T) {
+		var got []any
+		c := newConfig(
+			WithPanicRecovery(func(recovered any) { got = append(got, recovered) }),
+			WithRawResponseSink(func(raw []byte) { panic("raw sink") }),
+			WithSecretTransformer(func(raw []byte) ([]byte, error) { panic("transformer") }),
+		)
+
+		_, err := c.decoder.decode([]byte(`{"access_token":"token-123"}`))
+		assert.ErrorContainsf(t, err, "secret transformer panicked: transformer", "decode()")
+		assert.Equalf(t, []any{"raw sink", "transformer"}, got, "WithPanicRecovery()")
+	})
+
+	t.Run("token post processor panics, recovers panic and fails post-process", func(t *testing.T) {
+		var got []any
+		c := newConfig(
+			WithPanicRecovery(func(recovered any) { got = append(got, recovered) }),
+			WithTokenPostProcessor(func(t Token) (Token, error) { panic("post processor") }),
+		)
+
+		_, err := postProcess(c.postProcessor, Token{AccessToken: "token-123"}, nil)
+		assert.ErrorContainsf(t, err, "token post processor panicked: post processor", "postProcess()")
+		assert.Equalf(t, []any{"post processor"}, got, "WithPanicRecovery()")
+	})
+
+	t.Run("refresh predicate panics, recovers panic and doesn't require refresh", func(t *testing.T) {
+		var got []any
+		c := newConfig(
+			WithPanicRecovery(func(recovered any) { got = append(got, recovered) }),
+			WithRefreshPredicate(func(current Token, now time.Time) bool { panic("refresh predicate") }),
+		)
+
+		assert.Falsef(t, c.refreshRequired(Token{AccessToken: "token-123"}, now, now), "refreshRequired()")
+		assert.Equalf(t, []any{"refresh predicate"}, got, "WithPanicRecovery()")
+	})
+
+	t.Run("clock drift callback panics, recovers panic", func(t *testing.T) {
+		c := newConfig(
+			WithPanicRecovery(nil),
+			WithMaxClockDrift(time.Second, func(drift time.Duration) { panic("clock drift") }),
+		)
+		assert.NotPanicsf(t, func() { c.clockDrift.report(time.Minute) }, "report()")
+	})
+
+	t.Run("panic recovery not set, callback panics, panic propagates", func(t *testing.T) {
+		c := newConfig(WithOnWarning(func(err error) { panic("on warning") }))
+		assert.Panicsf(t, func() { c.onWarning(errors.New("error")) }, "onWarning()")
+	})
+}
@@ -0,0 +1,25 @@
+package token
+
+import (
+	"fmt"
+)
+
+// WithTokenPostProcessor sets a function applied to each token fetched from the adapter, e.g. to normalise the token
+// type or strip a "Bearer " prefix some providers include in the access token, so normalisations apply to every adapter
+// without being implemented by each. An error fails the fetch.
+func WithTokenPostProcessor(fn func(Token) (Token, error)) Option {
+	return func(c *config) { c.postProcessor = fn }
+}
+
+// postProcess applies the token post processor to a token fetched from the adapter, if set and the fetch succeeded
+func postProcess(postProcessor func(Token) (Token, error), t Token, err error) (Token, error) {
+	if err != nil || postProcessor == nil {
+		return t, err
+	}
+
+	t, err = postProcessor(t)
+	if err != nil {
+		return Token{}, fmt.Errorf("unable to post-process token: %w", err)
+	}
+	return t, nil
+}
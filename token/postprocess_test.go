@@ -0,0 +1,80 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTokenPostProcessor(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	normalize := func(t Token) (Token, error) {
+		t.AccessToken = strings.TrimPrefix(strings.TrimSpace(t.AccessToken), "Bearer ")
+		t.TokenType = "Bearer"
+		return t, nil
+	}
+
+	t.Run("post processor set, returns post-processed token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: " Bearer token-123\n", TokenType: "bearer"}, nil).Once()
+
+		f := New(mAdapter, WithNowFunc(func() time.Time { return now }), WithTokenPostProcessor(normalize))
+		got, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, Token{AccessToken: "token-123", TokenType: "Bearer"}, got, "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("post processor returns error, returns error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+
+		f := New(mAdapter,
+			WithNowFunc(func() time.Time { return now }),
+			WithTokenPostProcessor(func(t Token) (Token, error) { return Token{}, errors.New("error") }),
+		)
+		_, err := f.Fetch(context.Background())
+		assert.ErrorContainsf(t, err, "unable to post-process token: error", "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("adapter returns error, returns error without calling post processor", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+
+		var calls int
+		f := New(mAdapter,
+			WithNowFunc(func() time.Time { return now }),
+			WithTokenPostProcessor(func(t Token) (Token, error) {
+				calls++
+				return t, nil
+			}),
+		)
+		_, err := f.Fetch(context.Background())
+		assert.Errorf(t, err, "Fetch()")
+		assert.Equalf(t, 0, calls, "WithTokenPostProcessor()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("post processor set on multi fetcher, returns post-processed tokens", func(t *testing.T) {
+		mAdapter := new(mockMultiAdapter)
+		mAdapter.On("FetchAll", mock.Anything).Return(map[string]Token{
+			"a": {AccessToken: "Bearer token-a"},
+			"b": {AccessToken: "token-b"},
+		}, nil).Once()
+
+		f := NewMulti(mAdapter, WithNowFunc(func() time.Time { return now }), WithTokenPostProcessor(normalize))
+		got, err := f.FetchNamed(context.Background(), "a")
+		assert.NoErrorf(t, err, "FetchNamed()")
+		assert.Equalf(t, Token{AccessToken: "token-a", TokenType: "Bearer"}, got, "FetchNamed()")
+		assert.Equalf(t, map[string]Token{
+			"a": {AccessToken: "token-a", TokenType: "Bearer"},
+			"b": {AccessToken: "token-b", TokenType: "Bearer"},
+		}, f.tokens, "FetchNamed()")
+		mAdapter.AssertExpectations(t)
+	})
+}
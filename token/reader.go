@@ -0,0 +1,83 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// WithMaxResponseSize limits the number of bytes the reader, fs, Kubernetes service account token and GCP metadata
+// adapters read from their source, failing with ErrResponseTooLarge if it is larger, so a misbehaving source can't
+// exhaust memory. It also limits introspection responses. Sources are read in full by default.
+func WithMaxResponseSize(size int64) Option {
+	return func(c *config) { c.maxResponseSize = size }
+}
+
+// NewReaderAdapter returns an Adapter reading the raw secret value from the stream returned by open and parsing it into
+// a token. The stream is read fully and closed on each fetch. Options affecting how secret values are parsed, e.g.
+// WithStrictJSON, are applied.
+func NewReaderAdapter(open func() (io.ReadCloser, error), opts ...Option) Adapter {
+	return newReaderAdapter(open, "reader", opts...)
+}
+
+func newReaderAdapter(open func() (io.ReadCloser, error), source string, opts ...Option) readerAdapter {
+	c := newConfig(opts...)
+	return readerAdapter{
+		open:    open,
+		source:  source,
+		decoder: c.decoder,
+		maxSize: c.maxResponseSize,
+	}
+}
+
+// NewFSAdapter returns an Adapter reading the raw secret value from the named file in fsys, e.g. an embed.FS, and
+// parsing it into a token
+func NewFSAdapter(fsys fs.FS, name string, opts ...Option) Adapter {
+	return newReaderAdapter(func() (io.ReadCloser, error) { return fsys.Open(name) }, "fs["+name+"]", opts...)
+}
+
+type readerAdapter struct {
+	open    func() (io.ReadCloser, error)
+	source  string
+	decoder secretDecoder
+	maxSize int64
+}
+
+func (a readerAdapter) Fetch(_ context.Context) (Token, error) {
+	raw, err := a.read()
+	if err != nil {
+		return Token{}, sourceError("read token", a.source, err)
+	}
+
+	t, err := a.decoder.decode(raw)
+	if err != nil {
+		return Token{}, sourceError("parse token", a.source, err)
+	}
+	return t, nil
+}
+
+func (a readerAdapter) read() ([]byte, error) {
+	r, err := a.open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return readAll(r, a.maxSize)
+}
+
+// readAll reads r in full, failing with ErrResponseTooLarge if it is larger than maxSize, if set
+func readAll(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, maxSize)
+	}
+	return b, nil
+}
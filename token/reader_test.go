@@ -0,0 +1,140 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type mockReadCloser struct {
+	io.Reader
+	closed bool
+}
+
+func (r *mockReadCloser) Close() error {
+	r.closed = true
+	return nil
+}
+
+func Test_readerAdapter_Fetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		reader     *mockReadCloser
+		openErr    error
+		opts       []Option
+		want       Token
+		wantClosed bool
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "reader returns valid secret, returns token and closes reader",
+			reader:     &mockReadCloser{Reader: strings.NewReader(`{"access_token":"token-123","expiry":1893542400}`)},
+			want:       Token{AccessToken: "token-123", Expiry: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)},
+			wantClosed: true,
+			wantErr:    assert.NoError,
+		},
+		{
+			name:       "reader returns invalid secret, returns error and closes reader",
+			reader:     &mockReadCloser{Reader: strings.NewReader(`{invalid-json]`)},
+			wantClosed: true,
+			wantErr:    assert.Error,
+		},
+		{
+			name:       "strict option set, reader returns secret with unknown field, returns error",
+			reader:     &mockReadCloser{Reader: strings.NewReader(`{"access_token":"token-123","unknown":true}`)},
+			opts:       []Option{WithStrictJSON()},
+			wantClosed: true,
+			wantErr:    assert.Error,
+		},
+		{
+			name:       "reader returns error, returns error and closes reader",
+			reader:     &mockReadCloser{Reader: io.MultiReader(strings.NewReader(`{"access`), &errReader{})},
+			wantClosed: true,
+			wantErr:    assert.Error,
+		},
+		{
+			name:       "max response size set, reader returns secret within size, returns token",
+			reader:     &mockReadCloser{Reader: strings.NewReader(`{"access_token":"token-123"}`)},
+			opts:       []Option{WithMaxResponseSize(28)},
+			want:       Token{AccessToken: "token-123"},
+			wantClosed: true,
+			wantErr:    assert.NoError,
+		},
+		{
+			name:       "max response size set, reader returns secret exceeding size, returns error",
+			reader:     &mockReadCloser{Reader: strings.NewReader(`{"access_token":"token-123"}`)},
+			opts:       []Option{WithMaxResponseSize(27)},
+			wantClosed: true,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrResponseTooLarge, i...)
+			},
+		},
+		{
+			name:    "open returns error, returns error",
+			reader:  &mockReadCloser{},
+			openErr: errors.New("error"),
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open := func() (io.ReadCloser, error) {
+				if tt.openErr != nil {
+					return nil, tt.openErr
+				}
+				return tt.reader, nil
+			}
+			got, err := NewReaderAdapter(open, tt.opts...).Fetch(context.Background())
+			assert.Equalf(t, tt.wantClosed, tt.reader.closed, "Fetch()")
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("error")
+}
+
+func TestNewFSAdapter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"token.json": {Data: []byte(`{"access_token":"token-123"}`)},
+	}
+
+	tests := []struct {
+		name     string
+		fileName string
+		want     Token
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "file exists, returns token",
+			fileName: "token.json",
+			want:     Token{AccessToken: "token-123"},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "file does not exist, returns error",
+			fileName: "missing.json",
+			wantErr:  assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFSAdapter(fsys, tt.fileName).Fetch(context.Background())
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package token
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// RedisClient is the subset of a Redis client used to share cached tokens between processes. A small wrapper around a
+// client such as go-redis satisfies it.
+type RedisClient interface {
+	// Get returns the value stored under the key, or nil without an error if the key doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value under the key, expiring it after the ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type redisCacheConfig struct {
+	ttl          time.Duration
+	expiryBuffer time.Duration
+	aead         cipher.AEAD
+	err          error
+	fields       []string
+	decoder      secretDecoder
+	logger       *slog.Logger
+}
+
+var defaultRedisCacheConfig = redisCacheConfig{
+	ttl:          5 * time.Minute,
+	expiryBuffer: time.Minute,
+}
+
+type RedisCacheOption func(*redisCacheConfig)
+
+// WithRedisCacheTTL sets how long tokens without an expiry date are cached in Redis
+func WithRedisCacheTTL(ttl time.Duration) RedisCacheOption {
+	return func(c *redisCacheConfig) { c.ttl = ttl }
+}
+
+// WithRedisCacheExpiryBuffer sets the duration before the expiry date when a token is evicted from Redis. It should be
+// at least the token expiry buffer of the Fetcher, so the Fetcher isn't served a token it would refresh.
+func WithRedisCacheExpiryBuffer(buffer time.Duration) RedisCacheOption {
+	return func(c *redisCacheConfig) { c.expiryBuffer = buffer }
+}
+
+// WithRedisCacheEncryptionKey encrypts tokens cached in Redis with AES-GCM, using a key of 16, 24 or 32 bytes to select
+// AES-128, AES-192 or AES-256. Tokens are cached in plaintext by default, readable by anyone with access to Redis, which
+// NewRedisCachedAdapter logs a warning about.
+// Cached values that can't be decrypted, e.g. after the key is rotated, are treated as a cache miss. If the key is
+// invalid, fetches fail.
+func WithRedisCacheEncryptionKey(key []byte) RedisCacheOption {
+	return func(c *redisCacheConfig) {
+		c.aead, c.err = newAEAD(key)
+	}
+}
+
+// WithRedisCachePersistFields limits the fields of tokens cached in Redis to the access token, the expiry date and the
+// fields named, by their JSON names, e.g. "token_type", "refresh_token", "created_at", "scope" or the name of a field
+// held in Extra, so a compromised Redis exposes less. Other fields are missing from tokens read from Redis. All fields
+// are cached by default.
+func WithRedisCachePersistFields(fields ...string) RedisCacheOption {
+	return func(c *redisCacheConfig) { c.fields = append([]string{}, fields...) }
+}
+
+// WithRedisCacheJSONUnmarshaler sets the Unmarshaler tokens read from Redis are parsed with, as WithJSONUnmarshaler does
+// for the built-in adapters, defaulting to encoding/json
+func WithRedisCacheJSONUnmarshaler(unmarshaler Unmarshaler) RedisCacheOption {
+	return func(c *redisCacheConfig) { c.decoder.unmarshaler = unmarshaler }
+}
+
+// WithRedisCacheLogger sets the logger NewRedisCachedAdapter warns with if tokens are cached in plaintext, defaulting to
+// slog.Default
+func WithRedisCacheLogger(logger *slog.Logger) RedisCacheOption {
+	return func(c *redisCacheConfig) { c.logger = logger }
+}
+
+// persisted returns the fields of the token to cache in Redis
+func (c redisCacheConfig) persisted(t Token) Token {
+	if c.fields == nil {
+		return t
+	}
+
+	p := Token{AccessToken: t.AccessToken, Expiry: t.Expiry}
+	if slices.Contains(c.fields, "token_type") {
+		p.TokenType = t.TokenType
+	}
+	if slices.Contains(c.fields, "refresh_token") {
+		p.RefreshToken = t.RefreshToken
+	}
+	if slices.Contains(c.fields, "created_at") {
+		p.CreatedAt = t.CreatedAt
+	}
+	if slices.Contains(c.fields, "scope") {
+		p.Scopes = t.Scopes
+	}
+	for name, value := range t.Extra {
+		if !slices.Contains(c.fields, name) {
+			continue
+		}
+		if p.Extra == nil {
+			p.Extra = make(map[string]string)
+		}
+		p.Extra[name] = value
+	}
+	return p
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis cache encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewRedisCachedAdapter returns an Adapter sharing tokens between processes through Redis, e.g. across a horizontally
+// scaled fleet. Tokens are read from the key, falling back to the inner Adapter if the key is missing, unreadable or
+// holds an expiring token, and the fetched token is then stored under the key. Caching is best effort, so Redis
+// failures fall back to the inner Adapter rather than failing the fetch. A warning is logged if tokens are cached in
+// plaintext, without an encryption key.
+func NewRedisCachedAdapter(inner Adapter, client RedisClient, key string, opts ...RedisCacheOption) Adapter {
+	c := defaultRedisCacheConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.aead == nil && c.err == nil {
+		logger := c.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("redis cache stores tokens in plaintext without an encryption key", "key", key)
+	}
+	return redisCachedAdapter{
+		inner:  inner,
+		client: client,
+		key:    key,
+		config: c,
+		clock:  clock.NewSystem(),
+	}
+}
+
+type redisCachedAdapter struct {
+	inner  Adapter
+	client RedisClient
+	key    string
+	config redisCacheConfig
+	clock  clock.Clock
+}
+
+func (a redisCachedAdapter) Fetch(ctx context.Context) (Token, error) {
+	if a.config.err != nil {
+		return Token{}, a.config.err
+	}
+	if t, ok := a.cached(ctx); ok {
+		return t, nil
+	}
+
+	t, err := a.inner.Fetch(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	a.store(ctx, t)
+	return t, nil
+}
+
+// cached returns the token cached in Redis, treating any failure to read it as a miss
+func (a redisCachedAdapter) cached(ctx context.Context) (Token, bool) {
+	raw, err := a.client.Get(ctx, a.key)
+	if err != nil || raw == nil {
+		return Token{}, false
+	}
+	if raw, err = a.decrypt(raw); err != nil {
+		return Token{}, false
+	}
+
+	t, err := unmarshalToken(raw, a.config.decoder.unmarshal)
+	if err != nil {
+		return Token{}, false
+	}
+	return t, t.Valid(a.clock.Now(), a.config.expiryBuffer)
+}
+
+// store caches the token in Redis until the expiry buffer before its expiry date. Failures are ignored, as the token
+// is refetched on the next miss.
+func (a redisCachedAdapter) store(ctx context.Context, t Token) {
+	ttl := a.config.ttl
+	if !t.Expiry.IsZero() {
+		ttl = a.clock.Until(t.Expiry) - a.config.expiryBuffer
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	raw, err := json.Marshal(a.config.persisted(t))
+	if err != nil {
+		return
+	}
+	if raw, err = a.encrypt(raw); err != nil {
+		return
+	}
+	_ = a.client.Set(ctx, a.key, raw, ttl)
+}
+
+// encrypt seals the cached value if an encryption key is set, prefixing it with a random nonce. The Redis key is
+// authenticated, so a value can't be moved to another key.
+func (a redisCachedAdapter) encrypt(raw []byte) ([]byte, error) {
+	if a.config.aead == nil {
+		return raw, nil
+	}
+
+	nonce := make([]byte, a.config.aead.NonceSize(), a.config.aead.NonceSize()+len(raw)+a.config.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return a.config.aead.Seal(nonce, nonce, raw, []byte(a.key)), nil
+}
+
+// decrypt opens the cached value if an encryption key is set
+func (a redisCachedAdapter) decrypt(raw []byte) ([]byte, error) {
+	if a.config.aead == nil {
+		return raw, nil
+	}
+
+	size := a.config.aead.NonceSize()
+	if len(raw) < size {
+		return nil, errors.New("cached value too short")
+	}
+	return a.config.aead.Open(nil, raw[:size], raw[size:], []byte(a.key))
+}
@@ -0,0 +1,424 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type mockRedisClient struct {
+	mock.Mock
+}
+
+func (m *mockRedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	args := m.Called(ctx, key)
+	raw, _ := args.Get(0).([]byte)
+	return raw, args.Error(1)
+}
+
+func (m *mockRedisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := m.Called(ctx, key, value, ttl)
+	return args.Error(0)
+}
+
+func TestNewRedisCachedAdapter(t *testing.T) {
+	inner := new(mockAdapter)
+	client := new(mockRedisClient)
+	u := &mockUnmarshaler{}
+	logger := slog.New(slog.DiscardHandler)
+
+	tests := []struct {
+		name       string
+		opts       []RedisCacheOption
+		wantConfig redisCacheConfig
+	}{
+		{
+			name:       "NewRedisCachedAdapter returns adapter with default values",
+			wantConfig: redisCacheConfig{ttl: 5 * time.Minute, expiryBuffer: time.Minute},
+		},
+		{
+			name:       "NewRedisCachedAdapter returns adapter with provided options",
+			opts:       []RedisCacheOption{WithRedisCacheTTL(time.Hour), WithRedisCacheExpiryBuffer(time.Second)},
+			wantConfig: redisCacheConfig{ttl: time.Hour, expiryBuffer: time.Second},
+		},
+		{
+			name:       "NewRedisCachedAdapter returns adapter with persist fields",
+			opts:       []RedisCacheOption{WithRedisCachePersistFields("token_type")},
+			wantConfig: redisCacheConfig{ttl: 5 * time.Minute, expiryBuffer: time.Minute, fields: []string{"token_type"}},
+		},
+		{
+			name:       "NewRedisCachedAdapter returns adapter with no persist fields",
+			opts:       []RedisCacheOption{WithRedisCachePersistFields()},
+			wantConfig: redisCacheConfig{ttl: 5 * time.Minute, expiryBuffer: time.Minute, fields: []string{}},
+		},
+		{
+			name:       "NewRedisCachedAdapter returns adapter with JSON unmarshaler",
+			opts:       []RedisCacheOption{WithRedisCacheJSONUnmarshaler(u)},
+			wantConfig: redisCacheConfig{ttl: 5 * time.Minute, expiryBuffer: time.Minute, decoder: secretDecoder{unmarshaler: u}},
+		},
+		{
+			name:       "NewRedisCachedAdapter returns adapter with logger",
+			opts:       []RedisCacheOption{WithRedisCacheLogger(logger)},
+			wantConfig: redisCacheConfig{ttl: 5 * time.Minute, expiryBuffer: time.Minute, logger: logger},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NewRedisCachedAdapter(inner, client, "token-key", tt.opts...).(redisCachedAdapter)
+			if !assert.Truef(t, ok, "NewRedisCachedAdapter() adapter type") {
+				return
+			}
+			assert.Equalf(t, tt.wantConfig, got.config, "NewRedisCachedAdapter()")
+			assert.Equalf(t, inner, got.inner, "NewRedisCachedAdapter()")
+			assert.Equalf(t, client, got.client, "NewRedisCachedAdapter()")
+			assert.Equalf(t, "token-key", got.key, "NewRedisCachedAdapter()")
+		})
+	}
+}
+
+func Test_redisCachedAdapter_Fetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+	cachedTok := Token{AccessToken: "cached-token-123", Expiry: now.Add(time.Hour)}
+
+	type mockOpts struct {
+		client  func(m *mockRedisClient)
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name     string
+		mockOpts mockOpts
+		want     Token
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name: "token cached, returns cached token",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return([]byte(`{"access_token":"cached-token-123","expiry":"2030-01-02T01:00:00Z"}`), nil).Once()
+				},
+			},
+			want:    cachedTok,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "token not cached, returns token from inner adapter and caches it until expiry buffer",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return(nil, nil).Once()
+					m.On("Set", mock.Anything, "token-key", []byte(`{"access_token":"token-123","expiry":"2030-01-02T01:00:00Z","created_at":"0001-01-01T00:00:00Z"}`), 59*time.Minute).Return(nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "token not cached, token without expiry, returns token from inner adapter and caches it for ttl",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return(nil, nil).Once()
+					m.On("Set", mock.Anything, "token-key", mock.Anything, 5*time.Minute).Return(nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+				},
+			},
+			want:    Token{AccessToken: "token-123"},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "token not cached, token expiring within expiry buffer, returns token from inner adapter without caching it",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return(nil, nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: now.Add(time.Second)}, nil).Once()
+				},
+			},
+			want:    Token{AccessToken: "token-123", Expiry: now.Add(time.Second)},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "cached token expiring within expiry buffer, returns token from inner adapter",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return([]byte(`{"access_token":"cached-token-123","expiry":"2030-01-02T00:00:30Z"}`), nil).Once()
+					m.On("Set", mock.Anything, "token-key", mock.Anything, 59*time.Minute).Return(nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "cached token invalid, treated as miss, returns token from inner adapter",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return([]byte(`{invalid-json]`), nil).Once()
+					m.On("Set", mock.Anything, "token-key", mock.Anything, 59*time.Minute).Return(nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "redis get returns error, returns token from inner adapter",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return(nil, errors.New("error")).Once()
+					m.On("Set", mock.Anything, "token-key", mock.Anything, 59*time.Minute).Return(nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "redis set returns error, returns token from inner adapter",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return(nil, nil).Once()
+					m.On("Set", mock.Anything, "token-key", mock.Anything, 59*time.Minute).Return(errors.New("error")).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name: "token not cached, inner adapter returns error, returns error",
+			mockOpts: mockOpts{
+				client: func(m *mockRedisClient) {
+					m.On("Get", mock.Anything, "token-key").Return(nil, nil).Once()
+				},
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+				},
+			},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mClient := new(mockRedisClient)
+			if tt.mockOpts.client != nil {
+				tt.mockOpts.client(mClient)
+			}
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			a := redisCachedAdapter{
+				inner:  mAdapter,
+				client: mClient,
+				key:    "token-key",
+				config: defaultRedisCacheConfig,
+				clock:  clock.NewFixed(now),
+			}
+			got, err := a.Fetch(context.Background())
+			mClient.AssertExpectations(t)
+			mAdapter.AssertExpectations(t)
+			if !tt.wantErr(t, err, "Fetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Fetch()")
+		})
+	}
+}
+
+func Test_redisCacheConfig_persisted(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{
+		AccessToken:  "token-123",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-token-123",
+		Expiry:       now.Add(time.Hour),
+		CreatedAt:    now,
+		Scopes:       []string{"read"},
+		Extra:        map[string]string{"signing_key": "key-123", "region": "eu-west-1"},
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   Token
+	}{
+		{
+			name:   "persist fields not set, returns all fields",
+			fields: nil,
+			want:   tok,
+		},
+		{
+			name:   "no persist fields, returns access token and expiry",
+			fields: []string{},
+			want:   Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)},
+		},
+		{
+			name:   "persist fields set, returns access token, expiry and persist fields",
+			fields: []string{"token_type", "created_at", "scope", "region"},
+			want: Token{
+				AccessToken: "token-123",
+				TokenType:   "Bearer",
+				Expiry:      now.Add(time.Hour),
+				CreatedAt:   now,
+				Scopes:      []string{"read"},
+				Extra:       map[string]string{"region": "eu-west-1"},
+			},
+		},
+		{
+			name:   "refresh token persist field set, returns refresh token",
+			fields: []string{"refresh_token"},
+			want:   Token{AccessToken: "token-123", RefreshToken: "refresh-token-123", Expiry: now.Add(time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := redisCacheConfig{fields: tt.fields}
+			assert.Equalf(t, tt.want, c.persisted(tok), "persisted(%v)", tok)
+		})
+	}
+}
+
+// memRedisClient is an in-memory RedisClient ignoring ttls
+type memRedisClient map[string][]byte
+
+func (c memRedisClient) Get(_ context.Context, key string) ([]byte, error) {
+	return c[key], nil
+}
+
+func (c memRedisClient) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	c[key] = value
+	return nil
+}
+
+func Test_redisCachedAdapter_Fetch_encryption(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	otherKey := []byte("fedcba9876543210fedcba9876543210")
+
+	newAdapter := func(client RedisClient, inner Adapter, key []byte) redisCachedAdapter {
+		a := NewRedisCachedAdapter(inner, client, "token-key", WithRedisCacheEncryptionKey(key)).(redisCachedAdapter)
+		a.clock = clock.NewFixed(now)
+		return a
+	}
+
+	t.Run("token cached with key, stores encrypted token readable with key", func(t *testing.T) {
+		client := memRedisClient{}
+		inner := new(mockAdapter)
+		inner.On("Fetch", mock.Anything).Return(tok, nil).Once()
+
+		got, err := newAdapter(client, inner, key).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		assert.NotContainsf(t, string(client["token-key"]), "token-123", "Fetch() cached value")
+
+		got, err = newAdapter(client, new(mockAdapter), key).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch() cached")
+		assert.Equalf(t, tok, got, "Fetch() cached")
+		inner.AssertExpectations(t)
+	})
+
+	t.Run("token cached with other key, treated as miss, returns token from inner adapter", func(t *testing.T) {
+		client := memRedisClient{}
+		inner := new(mockAdapter)
+		inner.On("Fetch", mock.Anything).Return(tok, nil).Twice()
+
+		_, err := newAdapter(client, inner, otherKey).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+
+		got, err := newAdapter(client, inner, key).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch() other key")
+		assert.Equalf(t, tok, got, "Fetch() other key")
+		inner.AssertExpectations(t)
+	})
+
+	t.Run("plaintext token cached, treated as miss, returns token from inner adapter", func(t *testing.T) {
+		client := memRedisClient{"token-key": []byte(`{"access_token":"cached-token-123"}`)}
+		inner := new(mockAdapter)
+		inner.On("Fetch", mock.Anything).Return(tok, nil).Once()
+
+		got, err := newAdapter(client, inner, key).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		inner.AssertExpectations(t)
+	})
+
+	t.Run("invalid key, returns error", func(t *testing.T) {
+		_, err := newAdapter(memRedisClient{}, new(mockAdapter), []byte("short")).Fetch(context.Background())
+		assert.Errorf(t, err, "Fetch()")
+	})
+}
+
+func TestNewRedisCachedAdapter_plaintextWarning(t *testing.T) {
+	const warning = "level=WARN msg=\"redis cache stores tokens in plaintext without an encryption key\" key=token-key"
+
+	tests := []struct {
+		name     string
+		opts     []RedisCacheOption
+		wantWarn bool
+	}{
+		{
+			name:     "without encryption key, logs warning",
+			wantWarn: true,
+		},
+		{
+			name: "with encryption key, doesn't log warning",
+			opts: []RedisCacheOption{WithRedisCacheEncryptionKey([]byte("0123456789abcdef"))},
+		},
+		{
+			name: "with invalid encryption key, doesn't log warning",
+			opts: []RedisCacheOption{WithRedisCacheEncryptionKey([]byte("key"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append(tt.opts, WithRedisCacheLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+			NewRedisCachedAdapter(new(mockAdapter), memRedisClient{}, "token-key", opts...)
+			assert.Equalf(t, tt.wantWarn, bytes.Contains(buf.Bytes(), []byte(warning)), "NewRedisCachedAdapter() logs %s", buf.String())
+		})
+	}
+
+	t.Run("logger not set, logs warning with default logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		defer slog.SetDefault(slog.Default())
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+		NewRedisCachedAdapter(new(mockAdapter), memRedisClient{}, "token-key")
+		assert.Truef(t, bytes.Contains(buf.Bytes(), []byte(warning)), "NewRedisCachedAdapter() logs %s", buf.String())
+	})
+}
+
+func Test_redisCachedAdapter_Fetch_unmarshaler(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	client := memRedisClient{"token-key": []byte(`{"access_token":"token-123","expiry":"2030-01-02T01:00:00Z"}`)}
+	u := &mockUnmarshaler{}
+
+	a := NewRedisCachedAdapter(new(mockAdapter), client, "token-key", WithRedisCacheJSONUnmarshaler(u)).(redisCachedAdapter)
+	a.clock = clock.NewFixed(now)
+	got, err := a.Fetch(context.Background())
+	assert.NoErrorf(t, err, "Fetch()")
+	assert.Equalf(t, Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}, got, "Fetch()")
+	assert.Equalf(t, 2, u.calls, "Fetch() unmarshaler calls")
+}
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// WithRefreshAheadProbability refreshes tokens early with increasing probability over the given fraction of their
+// lifetime before the expiry buffer, spreading refreshes across a fleet rather than all fetchers refreshing at the
+// buffer boundary. The probability rises linearly from 0 at the start of the window to 1 at the buffer. The lifetime
+// is measured from the token's created date, or from when it was fetched if the token has no created date.
+func WithRefreshAheadProbability(fraction float64) Option {
+	return func(c *config) { c.refreshAheadFraction = min(max(fraction, 0), 1) }
+}
+
+// newRand returns a random number generator seeded per Fetcher, so fetchers started together don't refresh together.
+// It isn't safe for concurrent use, so must be called while the Fetcher is locked.
+func newRand() func() float64 {
+	// #nosec G404 -- refresh timing doesn't need a cryptographically secure source
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())).Float64
+}
+
+// refreshAhead reports whether the token should be refreshed early, with a probability increasing over the refresh
+// ahead window
+func (f *Fetcher) refreshAhead(now time.Time) bool {
+	if f.config.refreshAheadFraction <= 0 || f.token.Expiry.IsZero() {
+		return false
+	}
+
+	issued := f.token.CreatedAt
+	if issued.IsZero() {
+		issued = f.fetchedAt
+	}
+	if issued.IsZero() {
+		return false
+	}
+
+	end := f.token.Expiry.Add(-f.config.expiryBuffer(f.token))
+	window := time.Duration(float64(f.token.Expiry.Sub(issued)) * f.config.refreshAheadFraction)
+	start := end.Add(-window)
+	if window <= 0 || now.Before(start) {
+		return false
+	}
+	return f.rand() < float64(now.Sub(start))/float64(window)
+}
@@ -0,0 +1,116 @@
+package token
+
+import (
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWithRefreshAheadProbability(t *testing.T) {
+	tests := []struct {
+		name     string
+		fraction float64
+		want     float64
+	}{
+		{name: "fraction within range, sets fraction", fraction: 0.1, want: 0.1},
+		{name: "fraction below range, sets 0", fraction: -1, want: 0},
+		{name: "fraction above range, sets 1", fraction: 2, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, newConfig(WithRefreshAheadProbability(tt.fraction)).refreshAheadFraction, "WithRefreshAheadProbability(%v)", tt.fraction)
+		})
+	}
+}
+
+func TestFetcher_refreshAhead(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	// Token issued 100 minutes ago expiring in 11 minutes: with a 1 minute buffer and a fraction of 0.2, the window runs
+	// from 12 minutes ago until 10 minutes from now, so it is 12/22 of the way through
+	type fields struct {
+		config    config
+		token     Token
+		fetchedAt time.Time
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		rand   float64
+		want   bool
+	}{
+		{
+			name: "refresh ahead not set, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute},
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(11 * time.Minute), CreatedAt: now.Add(-100 * time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "no expiry set, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshAheadFraction: 0.2},
+				token:  Token{AccessToken: "token-123", CreatedAt: now.Add(-100 * time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "no created date or fetched date, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshAheadFraction: 0.2},
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(11 * time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "before window, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshAheadFraction: 0.2},
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(time.Hour), CreatedAt: now.Add(-100 * time.Minute)},
+			},
+			want: false,
+		},
+		{
+			name: "within window, random below probability, returns true",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshAheadFraction: 0.2},
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(11 * time.Minute), CreatedAt: now.Add(-100 * time.Minute)},
+			},
+			rand: 0.5,
+			want: true,
+		},
+		{
+			name: "within window, random above probability, returns false",
+			fields: fields{
+				config: config{tokenExpiryBuffer: time.Minute, refreshAheadFraction: 0.2},
+				token:  Token{AccessToken: "token-123", Expiry: now.Add(11 * time.Minute), CreatedAt: now.Add(-100 * time.Minute)},
+			},
+			rand: 0.6,
+			want: false,
+		},
+		{
+			name: "within window, no created date, measures lifetime from fetched date, returns true",
+			fields: fields{
+				config:    config{tokenExpiryBuffer: time.Minute, refreshAheadFraction: 0.2},
+				token:     Token{AccessToken: "token-123", Expiry: now.Add(11 * time.Minute)},
+				fetchedAt: now.Add(-100 * time.Minute),
+			},
+			rand: 0.5,
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{
+				config:    tt.fields.config,
+				clock:     clock.NewFixed(now),
+				token:     tt.fields.token,
+				fetchedAt: tt.fields.fetchedAt,
+				rand:      func() float64 { return tt.rand },
+			}
+			assert.Equalf(t, tt.want, f.refreshAhead(now), "refreshAhead(%v)", now)
+		})
+	}
+}
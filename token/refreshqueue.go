@@ -0,0 +1,117 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RefreshQueue serialises the refreshes of all the fetchers it is shared by, e.g. several fetchers calling the same
+// backend. Refreshes are processed in the order they are queued, so no fetcher starves, with at least a minimum
+// interval between the end of one adapter call and the start of the next.
+type RefreshQueue struct {
+	minInterval time.Duration
+	clock       clock.Clock
+
+	mu       sync.Mutex
+	busy     bool
+	waiting  []chan struct{}
+	lastDone time.Time
+}
+
+// NewRefreshQueue returns a new RefreshQueue leaving at least minInterval between adapter calls
+func NewRefreshQueue(minInterval time.Duration) *RefreshQueue {
+	return &RefreshQueue{
+		minInterval: minInterval,
+		clock:       clock.NewSystem(),
+	}
+}
+
+// WithRefreshQueue queues the refreshes of the Fetcher in a RefreshQueue shared with other fetchers. A fetch whose
+// context is cancelled while queued leaves the queue.
+func WithRefreshQueue(q *RefreshQueue) Option {
+	return func(c *config) { c.refreshQueue = q }
+}
+
+// fetch calls fetch once the refresh reaches the front of the queue, or immediately if q is nil
+func (q *RefreshQueue) fetch(ctx context.Context, fetch func(ctx context.Context) (Token, error)) (Token, error) {
+	if q == nil {
+		return fetch(ctx)
+	}
+
+	if err := q.acquire(ctx); err != nil {
+		return Token{}, fmt.Errorf("unable to acquire refresh queue: %w", err)
+	}
+	defer q.release(true)
+	return fetch(ctx)
+}
+
+// acquire waits for the refresh to reach the front of the queue and for the minimum interval since the last adapter
+// call to pass
+func (q *RefreshQueue) acquire(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return q.waitInterval(ctx)
+	}
+	turn := make(chan struct{})
+	q.waiting = append(q.waiting, turn)
+	q.mu.Unlock()
+
+	select {
+	case <-turn:
+		return q.waitInterval(ctx)
+	case <-ctx.Done():
+		q.mu.Lock()
+		if i := slices.Index(q.waiting, turn); i >= 0 {
+			q.waiting = slices.Delete(q.waiting, i, i+1)
+			q.mu.Unlock()
+			return ctx.Err()
+		}
+		q.mu.Unlock()
+		// The turn was handed over as the context was cancelled, so pass it on
+		q.release(false)
+		return ctx.Err()
+	}
+}
+
+// waitInterval waits for the minimum interval since the last adapter call to pass, releasing the turn if the context
+// is cancelled
+func (q *RefreshQueue) waitInterval(ctx context.Context) error {
+	q.mu.Lock()
+	wait := q.clock.Until(q.lastDone.Add(q.minInterval))
+	q.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		q.release(false)
+		return ctx.Err()
+	}
+}
+
+// release hands the turn to the next queued refresh, if any, recording the end of the adapter call if it was made
+func (q *RefreshQueue) release(called bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if called {
+		q.lastDone = q.clock.Now()
+	}
+	if len(q.waiting) == 0 {
+		q.busy = false
+		return
+	}
+	close(q.waiting[0])
+	q.waiting = q.waiting[1:]
+}
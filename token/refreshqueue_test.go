@@ -0,0 +1,128 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewRefreshQueue(t *testing.T) {
+	t.Run("NewRefreshQueue returns refresh queue with min interval", func(t *testing.T) {
+		got := NewRefreshQueue(time.Second)
+		assert.Equalf(t, time.Second, got.minInterval, "NewRefreshQueue()")
+		assert.Equalf(t, clock.NewSystem(), got.clock, "NewRefreshQueue()")
+	})
+}
+
+func TestRefreshQueue_fetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+	fetch := func(ctx context.Context) (Token, error) { return tok, nil }
+
+	waitQueued := func(q *RefreshQueue, n int) {
+		assert.Eventually(t, func() bool {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			return len(q.waiting) == n
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("nil queue, fetches immediately", func(t *testing.T) {
+		var q *RefreshQueue
+		got, err := q.fetch(context.Background(), fetch)
+		assert.NoErrorf(t, err, "fetch()")
+		assert.Equalf(t, tok, got, "fetch()")
+	})
+
+	t.Run("queue idle, fetches and records end of call", func(t *testing.T) {
+		q := &RefreshQueue{clock: clock.NewFixed(now)}
+		got, err := q.fetch(context.Background(), fetch)
+		assert.NoErrorf(t, err, "fetch()")
+		assert.Equalf(t, tok, got, "fetch()")
+		assert.Falsef(t, q.busy, "fetch() busy")
+		assert.Equalf(t, now, q.lastDone, "fetch() lastDone")
+	})
+
+	t.Run("queue busy, fetches in order queued", func(t *testing.T) {
+		q := &RefreshQueue{clock: clock.NewFixed(now)}
+		assert.NoErrorf(t, q.acquire(context.Background()), "acquire()")
+
+		var mu sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+		for i := range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = q.fetch(context.Background(), func(ctx context.Context) (Token, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					order = append(order, i)
+					return tok, nil
+				})
+			}()
+			waitQueued(q, i+1)
+		}
+		q.release(true)
+		wg.Wait()
+		assert.Equalf(t, []int{0, 1, 2}, order, "fetch() order")
+	})
+
+	t.Run("queue busy, context cancelled while queued, leaves queue and returns error", func(t *testing.T) {
+		q := &RefreshQueue{clock: clock.NewFixed(now)}
+		assert.NoErrorf(t, q.acquire(context.Background()), "acquire()")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			_, err := q.fetch(ctx, fetch)
+			errs <- err
+		}()
+		waitQueued(q, 1)
+		cancel()
+		assert.ErrorIsf(t, <-errs, context.Canceled, "fetch()")
+		assert.Emptyf(t, q.waiting, "fetch() waiting")
+
+		q.release(true)
+		assert.Falsef(t, q.busy, "release() busy")
+	})
+
+	t.Run("last call within min interval, context cancelled while waiting, returns error and releases queue", func(t *testing.T) {
+		q := &RefreshQueue{minInterval: time.Hour, clock: clock.NewFixed(now), lastDone: now}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := q.fetch(ctx, fetch)
+		assert.ErrorIsf(t, err, context.DeadlineExceeded, "fetch()")
+		assert.Falsef(t, q.busy, "fetch() busy")
+		assert.Equalf(t, now, q.lastDone, "fetch() lastDone")
+	})
+
+	t.Run("last call before min interval, fetches immediately", func(t *testing.T) {
+		q := &RefreshQueue{minInterval: time.Hour, clock: clock.NewFixed(now), lastDone: now.Add(-time.Hour)}
+		got, err := q.fetch(context.Background(), fetch)
+		assert.NoErrorf(t, err, "fetch()")
+		assert.Equalf(t, tok, got, "fetch()")
+	})
+}
+
+func TestWithRefreshQueue(t *testing.T) {
+	t.Run("fetchers share refresh queue, refreshes through queue", func(t *testing.T) {
+		q := NewRefreshQueue(0)
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Twice()
+
+		a, b := New(mAdapter, WithRefreshQueue(q)), New(mAdapter, WithRefreshQueue(q))
+		assert.Samef(t, q, a.config.refreshQueue, "WithRefreshQueue()")
+		_, errA := a.Fetch(context.Background())
+		_, errB := b.Fetch(context.Background())
+		assert.NoErrorf(t, errA, "Fetch()")
+		assert.NoErrorf(t, errB, "Fetch()")
+		assert.Falsef(t, q.busy, "Fetch() busy")
+		mAdapter.AssertExpectations(t)
+	})
+}
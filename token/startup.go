@@ -0,0 +1,130 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"time"
+)
+
+// defaultStartupDelay is the delay before the first startup retry if WithStartupBackoff isn't given one
+const defaultStartupDelay = 100 * time.Millisecond
+
+// WithStartupBackoff retries failed adapter calls until the Fetcher has fetched its first token, for up to window from
+// its first call, e.g. to tolerate a secret backend that isn't reachable for the first seconds of a cold start. Retries
+// are delayed by initialDelay, doubling after each attempt, unless WithBackoff sets another strategy. Once a token has
+// been fetched, or the window has passed, failures are returned without retrying. Fetches and Validate wait on the
+// retries, and concurrent fetches share the outcome of the retries in flight rather than retrying alongside them.
+func WithStartupBackoff(window time.Duration, initialDelay time.Duration) Option {
+	return func(c *config) {
+		c.startupWindow = window
+		c.startupDelay = initialDelay
+		if c.startupDelay <= 0 {
+			c.startupDelay = defaultStartupDelay
+		}
+	}
+}
+
+// startupFetch fetches a token from the adapter in the refresh queue, retrying failures within the startup window if no
+// token has been fetched yet. It must be called while the Fetcher is locked. Between retries the Fetcher is unlocked and
+// the refresh queue released, so other calls aren't blocked on the backoff, and refreshes wait on the retries by
+// awaitStartup rather than making their own.
+func (f *Fetcher) startupFetch(ctx context.Context) (Token, error) {
+	if f.config.startupWindow <= 0 || f.started {
+		return f.config.refreshQueue.fetch(ctx, f.fetchFromAdapter)
+	}
+	if f.startupDeadline.IsZero() {
+		f.startupDeadline = f.clock.Now().Add(f.config.startupWindow)
+	}
+	done := make(chan struct{})
+	f.startup = done
+	defer func() {
+		close(done)
+		if f.startup == done {
+			f.startup = nil
+		}
+	}()
+
+	backoff := f.config.backoffStrategy()
+	for attempt := 1; ; attempt++ {
+		t, err := f.config.refreshQueue.fetch(ctx, f.fetchFromAdapter)
+		if err == nil {
+			f.started = true
+			return t, nil
+		}
+
+		wait := min(backoff.NextDelay(attempt), f.clock.Until(f.startupDeadline))
+		if wait <= 0 || !f.sleepUnlocked(ctx, wait) {
+			return Token{}, err
+		}
+		if f.closed {
+			return Token{}, ErrFetcherClosed
+		}
+		if f.started {
+			// Another fetch succeeded while unlocked, so the startup window no longer applies
+			return f.config.refreshQueue.fetch(ctx, f.fetchFromAdapter)
+		}
+	}
+}
+
+// awaitStartup waits for the startup retries of another call to complete, returning the token they cached, or their
+// error once the startup window has passed. Otherwise, e.g. if the context of the other call was done, the token is
+// refreshed again. It must be called while the Fetcher is locked, which it is again on return.
+func (f *Fetcher) awaitStartup(ctx context.Context) (Token, error) {
+	if !f.waitUnlocked(ctx, f.startup) {
+		return Token{}, ctx.Err()
+	}
+	if f.closed {
+		return Token{}, ErrFetcherClosed
+	}
+	if f.started && f.token.AccessToken != "" {
+		return f.token, nil
+	}
+	if f.lastRefreshErr != nil && !f.clock.Now().Before(f.startupDeadline) {
+		return Token{}, f.lastRefreshErr
+	}
+	return f.refresh(ctx)
+}
+
+// sleepUnlocked waits for d with the Fetcher unlocked, returning false if the context is done first. It must be called
+// while the Fetcher is locked, which it is again on return.
+func (f *Fetcher) sleepUnlocked(ctx context.Context, d time.Duration) bool {
+	f.mu.Unlock()
+	defer f.mu.Lock()
+	return sleep(ctx, f.clock, d)
+}
+
+// waitUnlocked waits for done to be closed with the Fetcher unlocked, returning false if the context is done first. It
+// must be called while the Fetcher is locked, which it is again on return.
+func (f *Fetcher) waitUnlocked(ctx context.Context, done <-chan struct{}) bool {
+	f.mu.Unlock()
+	defer f.mu.Lock()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sleeper is implemented by clocks that wait for a duration themselves, e.g. fake clocks advancing their time in tests
+type sleeper interface {
+	Sleep(ctx context.Context, d time.Duration) bool
+}
+
+// sleep waits for d on the clock, returning false if the context is done first
+func sleep(ctx context.Context, c clock.Clock, d time.Duration) bool {
+	if s, ok := c.(sleeper); ok {
+		return s.Sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,294 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock whose time only moves forwards when it sleeps, calling onSleep first if set
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	onSleep func(d time.Duration)
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *fakeClock) Until(t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) bool {
+	if c.onSleep != nil {
+		c.onSleep(d)
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return true
+}
+
+func TestWithStartupBackoff(t *testing.T) {
+	tests := []struct {
+		name         string
+		initialDelay time.Duration
+		wantDelay    time.Duration
+	}{
+		{name: "initial delay set, sets initial delay", initialDelay: time.Second, wantDelay: time.Second},
+		{name: "initial delay not set, sets default initial delay", initialDelay: 0, wantDelay: defaultStartupDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig(WithStartupBackoff(time.Minute, tt.initialDelay))
+			assert.Equalf(t, time.Minute, c.startupWindow, "WithStartupBackoff() window")
+			assert.Equalf(t, tt.wantDelay, c.startupDelay, "WithStartupBackoff() initial delay")
+		})
+	}
+}
+
+func TestFetcher_startupFetch(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+	err := errors.New("error")
+
+	type fields struct {
+		config  config
+		started bool
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		mockOpts    mockOpts
+		want        Token
+		wantStarted bool
+		wantSleeps  []time.Duration
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "startup backoff not set, adapter returns error, returns error without retrying",
+			fields: fields{config: config{}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, err).Once()
+			}},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "startup backoff set, adapter returns errors then token, retries and returns token",
+			fields: fields{config: config{startupWindow: time.Minute, startupDelay: time.Millisecond}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, err).Twice()
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			want:        tok,
+			wantStarted: true,
+			wantSleeps:  []time.Duration{time.Millisecond, 2 * time.Millisecond},
+			wantErr:     assert.NoError,
+		},
+		{
+			name:   "startup backoff set, adapter returns errors beyond window, returns error",
+			fields: fields{config: config{startupWindow: 15 * time.Millisecond, startupDelay: 10 * time.Millisecond}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				// Attempts at 0ms and 10ms, then a final attempt at the end of the window at 15ms
+				m.On("Fetch", mock.Anything).Return(Token{}, err).Times(3)
+			}},
+			wantSleeps: []time.Duration{10 * time.Millisecond, 5 * time.Millisecond},
+			wantErr:    assert.Error,
+		},
+		{
+			name:   "startup backoff and strategy set, adapter returns errors beyond window, retries with strategy delays",
+			fields: fields{config: config{startupWindow: 25 * time.Millisecond, startupDelay: time.Hour, backoff: constantBackoff{delay: 10 * time.Millisecond}}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				// Attempts at 0ms, 10ms and 20ms, then a final attempt at the end of the window at 25ms
+				m.On("Fetch", mock.Anything).Return(Token{}, err).Times(4)
+			}},
+			wantSleeps: []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 5 * time.Millisecond},
+			wantErr:    assert.Error,
+		},
+		{
+			name:   "startup backoff set, token fetched before, adapter returns error, returns error without retrying",
+			fields: fields{config: config{startupWindow: time.Minute, startupDelay: time.Millisecond}, started: true},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, err).Once()
+			}},
+			wantStarted: true,
+			wantErr:     assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			var gotSleeps []time.Duration
+			f := &Fetcher{
+				config:  tt.fields.config,
+				clock:   &fakeClock{now: now, onSleep: func(d time.Duration) { gotSleeps = append(gotSleeps, d) }},
+				adapter: mAdapter,
+				started: tt.fields.started,
+			}
+			f.mu.Lock()
+			got, err := f.startupFetch(context.Background())
+			f.mu.Unlock()
+			mAdapter.AssertExpectations(t)
+			assert.Equalf(t, tt.wantStarted, f.started, "startupFetch()")
+			assert.Equalf(t, tt.wantSleeps, gotSleeps, "startupFetch() sleeps")
+			if !tt.wantErr(t, err, "startupFetch()") {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "startupFetch()")
+		})
+	}
+
+	t.Run("startup backoff set, context cancelled while waiting, returns error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, err).Once()
+		f := &Fetcher{
+			config:  config{startupWindow: time.Hour, startupDelay: time.Hour},
+			clock:   clock.NewSystem(),
+			adapter: mAdapter,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		f.mu.Lock()
+		_, gotErr := f.startupFetch(ctx)
+		f.mu.Unlock()
+		assert.ErrorIsf(t, gotErr, err, "startupFetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("startup backoff set, waiting between retries, unlocks fetcher and releases refresh queue", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, err).Once()
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Once()
+		q := NewRefreshQueue(0)
+		f := &Fetcher{
+			config:  config{startupWindow: time.Minute, startupDelay: time.Second, refreshQueue: q},
+			adapter: mAdapter,
+		}
+		f.clock = &fakeClock{now: now, onSleep: func(time.Duration) {
+			assert.Truef(t, f.mu.TryLock(), "startupFetch() fetcher locked while waiting")
+			f.mu.Unlock()
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			assert.Falsef(t, q.busy, "startupFetch() refresh queue held while waiting")
+		}}
+
+		f.mu.Lock()
+		got, gotErr := f.startupFetch(context.Background())
+		f.mu.Unlock()
+		assert.NoErrorf(t, gotErr, "startupFetch()")
+		assert.Equalf(t, tok, got, "startupFetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("startup backoff set, fetcher closed while waiting, returns fetcher closed error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, err).Once()
+		f := &Fetcher{
+			config:  config{startupWindow: time.Minute, startupDelay: time.Second},
+			adapter: mAdapter,
+		}
+		f.clock = &fakeClock{now: now, onSleep: func(time.Duration) { assert.NoErrorf(t, f.Close(), "Close()") }}
+
+		f.mu.Lock()
+		_, gotErr := f.startupFetch(context.Background())
+		f.mu.Unlock()
+		assert.ErrorIsf(t, gotErr, ErrFetcherClosed, "startupFetch()")
+		mAdapter.AssertExpectations(t)
+	})
+}
+
+func TestFetcher_Fetch_startupBackoff(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123", Expiry: now.Add(time.Hour)}
+	err := errors.New("error")
+
+	fetchConcurrently := func(f *Fetcher, n int) ([]Token, []error) {
+		got := make([]Token, n)
+		gotErrs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got[i], gotErrs[i] = f.Fetch(context.Background())
+			}()
+		}
+		wg.Wait()
+		return got, gotErrs
+	}
+
+	t.Run("concurrent fetches, adapter returns errors then token, share retries and return token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, err).Times(3)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil)
+		f := &Fetcher{
+			config:  config{startupWindow: time.Minute, startupDelay: time.Millisecond},
+			adapter: mAdapter,
+			// Give the other fetches time to wait on the retries
+			clock: &fakeClock{now: now, onSleep: func(time.Duration) { time.Sleep(time.Millisecond) }},
+		}
+
+		got, gotErrs := fetchConcurrently(f, 20)
+		for i := range got {
+			assert.NoErrorf(t, gotErrs[i], "Fetch()")
+			assert.Equalf(t, tok, got[i], "Fetch()")
+		}
+		mAdapter.AssertNumberOfCalls(t, "Fetch", 4)
+	})
+
+	t.Run("concurrent fetches, adapter returns errors beyond window, share retries and return error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, err)
+		f := &Fetcher{
+			config:  config{startupWindow: 15 * time.Millisecond, startupDelay: 10 * time.Millisecond},
+			adapter: mAdapter,
+			clock:   &fakeClock{now: now, onSleep: func(time.Duration) { time.Sleep(time.Millisecond) }},
+		}
+
+		_, gotErrs := fetchConcurrently(f, 20)
+		for _, gotErr := range gotErrs {
+			assert.ErrorIsf(t, gotErr, err, "Fetch()")
+		}
+		mAdapter.AssertNumberOfCalls(t, "Fetch", 3)
+	})
+
+	t.Run("fetch waiting on retries, context cancelled, returns context error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		f := &Fetcher{
+			config:  config{startupWindow: time.Minute, startupDelay: time.Millisecond},
+			clock:   clock.NewFixed(now),
+			adapter: mAdapter,
+			startup: make(chan struct{}),
+		}
+
+		_, gotErr := f.Fetch(ctx)
+		assert.ErrorIsf(t, gotErr, context.Canceled, "Fetch()")
+		mAdapter.AssertNotCalled(t, "Fetch", mock.Anything)
+	})
+}
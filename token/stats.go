@@ -0,0 +1,79 @@
+package token
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyWindow is the number of recent refreshes the latency summary of FetcherStats covers
+const latencyWindow = 64
+
+// FetcherStats summarises the fetches of a Fetcher, e.g. for a debug endpoint
+type FetcherStats struct {
+	// Hits is the number of fetches served from the cache
+	Hits uint64
+	// Refreshes is the number of adapter calls, successful or not
+	Refreshes uint64
+	// Failures is the number of refreshes that failed
+	Failures uint64
+	// LatencyMin, LatencyMax and LatencyAvg summarise the latency of recent refreshes
+	LatencyMin time.Duration
+	LatencyMax time.Duration
+	LatencyAvg time.Duration
+}
+
+// fetcherStats accounts the fetches of a Fetcher independently of its lock, so reading stats doesn't wait for a refresh
+type fetcherStats struct {
+	hits     atomic.Uint64
+	failures atomic.Uint64
+
+	mu        sync.Mutex
+	latencies [latencyWindow]time.Duration
+	recorded  int
+}
+
+// recordLatency adds the latency of a refresh to the ring buffer of recent latencies
+func (s *fetcherStats) recordLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[s.recorded%latencyWindow] = latency
+	s.recorded++
+}
+
+// reset clears the counts and recent latencies
+func (s *fetcherStats) reset() {
+	s.hits.Store(0)
+	s.failures.Store(0)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = [latencyWindow]time.Duration{}
+	s.recorded = 0
+}
+
+// Stats returns counts of cache hits, refreshes and failures, and a summary of the latency of recent refreshes
+func (f *Fetcher) Stats() FetcherStats {
+	stats := FetcherStats{
+		Hits:      f.stats.hits.Load(),
+		Refreshes: f.refreshes.Load(),
+		Failures:  f.stats.failures.Load(),
+	}
+
+	f.stats.mu.Lock()
+	defer f.stats.mu.Unlock()
+	n := min(f.stats.recorded, latencyWindow)
+	if n == 0 {
+		return stats
+	}
+
+	var total time.Duration
+	stats.LatencyMin = f.stats.latencies[0]
+	for _, latency := range f.stats.latencies[:n] {
+		stats.LatencyMin = min(stats.LatencyMin, latency)
+		stats.LatencyMax = max(stats.LatencyMax, latency)
+		total += latency
+	}
+	stats.LatencyAvg = total / time.Duration(n)
+	return stats
+}
@@ -0,0 +1,58 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFetcher_Stats(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		want      FetcherStats
+	}{
+		{
+			name: "no refreshes, returns zero latencies",
+			want: FetcherStats{},
+		},
+		{
+			name:      "refreshes recorded, returns latency summary",
+			latencies: []time.Duration{2 * time.Second, time.Second, 3 * time.Second},
+			want:      FetcherStats{LatencyMin: time.Second, LatencyMax: 3 * time.Second, LatencyAvg: 2 * time.Second},
+		},
+		{
+			name:      "more refreshes than latency window, returns latency summary of recent refreshes",
+			latencies: append([]time.Duration{time.Hour}, slices.Repeat([]time.Duration{time.Second}, latencyWindow)...),
+			want:      FetcherStats{LatencyMin: time.Second, LatencyMax: time.Second, LatencyAvg: time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{}
+			for _, latency := range tt.latencies {
+				f.stats.recordLatency(latency)
+			}
+			assert.Equalf(t, tt.want, f.Stats(), "Stats()")
+		})
+	}
+
+	t.Run("fetches served and refreshed, returns counts", func(t *testing.T) {
+		now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+		mAdapter.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+
+		f := &Fetcher{config: defaultConfig, clock: clock.NewFixed(now), adapter: mAdapter}
+		for range 4 {
+			_, _ = f.Fetch(context.Background())
+		}
+		assert.Equalf(t, FetcherStats{Hits: 2, Refreshes: 2, Failures: 1}, f.Stats(), "Stats()")
+		mAdapter.AssertExpectations(t)
+	})
+}
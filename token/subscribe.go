@@ -0,0 +1,61 @@
+package token
+
+import (
+	"slices"
+)
+
+// Subscribe returns a channel receiving a clone of the token each time the cached token is refreshed, e.g. to
+// re-establish a long-lived connection with the new token. The channel holds only the latest token, so a slow
+// subscriber misses intermediate tokens rather than stalling refreshes. Unsubscribe releases the channel, and Close
+// closes the channels of all subscribers.
+func (f *Fetcher) Subscribe() <-chan Token {
+	f.subsMu.Lock()
+	defer f.subsMu.Unlock()
+
+	ch := make(chan Token, 1)
+	if f.subsClosed {
+		close(ch)
+		return ch
+	}
+	f.subs = append(f.subs, ch)
+	return ch
+}
+
+// Unsubscribe stops delivering tokens to a channel returned by Subscribe and closes it
+func (f *Fetcher) Unsubscribe(ch <-chan Token) {
+	f.subsMu.Lock()
+	defer f.subsMu.Unlock()
+
+	i := slices.IndexFunc(f.subs, func(sub chan Token) bool { return sub == ch })
+	if i < 0 {
+		return
+	}
+	close(f.subs[i])
+	f.subs = slices.Delete(f.subs, i, i+1)
+}
+
+// publish delivers the token to each subscriber, replacing any token not yet received
+func (f *Fetcher) publish(t Token) {
+	f.subsMu.Lock()
+	defer f.subsMu.Unlock()
+
+	for _, ch := range f.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- t.Clone()
+	}
+}
+
+// closeSubscribers closes the channels of all subscribers
+func (f *Fetcher) closeSubscribers() {
+	f.subsMu.Lock()
+	defer f.subsMu.Unlock()
+
+	for _, ch := range f.subs {
+		close(ch)
+	}
+	f.subs = nil
+	f.subsClosed = true
+}
@@ -0,0 +1,83 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestFetcher_Subscribe(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok1 := Token{AccessToken: "token-1"}
+	tok2 := Token{AccessToken: "token-2"}
+
+	newFetcher := func(m *mockAdapter) *Fetcher {
+		return &Fetcher{config: defaultConfig, clock: clock.NewFixed(now), adapter: m}
+	}
+
+	t.Run("token refreshed, delivers token to each subscriber", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok1, nil).Once()
+		f := newFetcher(mAdapter)
+
+		ch1, ch2 := f.Subscribe(), f.Subscribe()
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok1, <-ch1, "Subscribe() first subscriber")
+		assert.Equalf(t, tok1, <-ch2, "Subscribe() second subscriber")
+	})
+
+	t.Run("token refreshed twice without receiving, delivers latest token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok1, nil).Once()
+		mAdapter.On("Fetch", mock.Anything).Return(tok2, nil).Once()
+		f := newFetcher(mAdapter)
+
+		ch := f.Subscribe()
+		_, _ = f.Fetch(context.Background(), ForceRefresh())
+		_, _ = f.Fetch(context.Background(), ForceRefresh())
+		assert.Equalf(t, tok2, <-ch, "Subscribe()")
+		assert.Lenf(t, ch, 0, "Subscribe()")
+	})
+
+	t.Run("cached token served, delivers nothing", func(t *testing.T) {
+		f := newFetcher(new(mockAdapter))
+		f.token = tok1
+
+		ch := f.Subscribe()
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Lenf(t, ch, 0, "Subscribe()")
+	})
+
+	t.Run("unsubscribed, closes channel and delivers nothing", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok1, nil).Once()
+		f := newFetcher(mAdapter)
+
+		ch := f.Subscribe()
+		f.Unsubscribe(ch)
+		_, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		_, ok := <-ch
+		assert.Falsef(t, ok, "Unsubscribe() channel closed")
+		assert.Emptyf(t, f.subs, "Unsubscribe() subscribers")
+
+		f.Unsubscribe(ch)
+	})
+
+	t.Run("fetcher closed, closes channels of subscribers", func(t *testing.T) {
+		f := newFetcher(new(mockAdapter))
+
+		ch := f.Subscribe()
+		assert.NoErrorf(t, f.Close(), "Close()")
+		_, ok := <-ch
+		assert.Falsef(t, ok, "Close() channel closed")
+
+		_, ok = <-f.Subscribe()
+		assert.Falsef(t, ok, "Subscribe() after Close() channel closed")
+	})
+}
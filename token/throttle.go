@@ -0,0 +1,52 @@
+package token
+
+import (
+	"context"
+	"fmt"
+)
+
+// Throttle limits the number of concurrent calls across all the adapters it wraps, e.g. to cap calls to a backend
+// shared by several fetchers
+type Throttle struct {
+	sem chan struct{}
+}
+
+// NewThrottle returns a new Throttle allowing up to maxConcurrent concurrent adapter calls
+func NewThrottle(maxConcurrent int) *Throttle {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &Throttle{
+		sem: make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Wrap returns an Adapter calling the inner Adapter within the limit of the Throttle
+func (t *Throttle) Wrap(inner Adapter) Adapter {
+	return throttledAdapter{
+		throttle: t,
+		inner:    inner,
+	}
+}
+
+// NewThrottledAdapter returns an Adapter limiting the number of concurrent calls to the inner Adapter to maxConcurrent.
+// Use a Throttle to share the limit between several adapters.
+func NewThrottledAdapter(inner Adapter, maxConcurrent int) Adapter {
+	return NewThrottle(maxConcurrent).Wrap(inner)
+}
+
+type throttledAdapter struct {
+	throttle *Throttle
+	inner    Adapter
+}
+
+func (a throttledAdapter) Fetch(ctx context.Context) (Token, error) {
+	select {
+	case a.throttle.sem <- struct{}{}:
+	case <-ctx.Done():
+		return Token{}, fmt.Errorf("unable to acquire throttle: %w", ctx.Err())
+	}
+	defer func() { <-a.throttle.sem }()
+
+	return a.inner.Fetch(ctx)
+}
@@ -0,0 +1,93 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewThrottle(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxConcurrent int
+		wantCap       int
+	}{
+		{name: "positive max, returns throttle with max capacity", maxConcurrent: 5, wantCap: 5},
+		{name: "zero max, returns throttle with capacity of one", maxConcurrent: 0, wantCap: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewThrottle(tt.maxConcurrent)
+			assert.Equalf(t, tt.wantCap, cap(got.sem), "NewThrottle(%v)", tt.maxConcurrent)
+		})
+	}
+}
+
+func Test_throttledAdapter_Fetch(t *testing.T) {
+	tok := Token{AccessToken: "token-123"}
+
+	t.Run("inner adapter returns token, returns token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Once()
+
+		got, err := NewThrottledAdapter(mAdapter, 1).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("inner adapter returns error, returns error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+
+		_, err := NewThrottledAdapter(mAdapter, 1).Fetch(context.Background())
+		assert.Errorf(t, err, "Fetch()")
+	})
+
+	t.Run("throttle full and context cancelled, returns error without calling inner adapter", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		throttle := NewThrottle(1)
+		throttle.sem <- struct{}{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := throttle.Wrap(mAdapter).Fetch(ctx)
+		assert.ErrorIsf(t, err, context.Canceled, "Fetch()")
+		mAdapter.AssertNotCalled(t, "Fetch", mock.Anything)
+	})
+
+	t.Run("shared throttle, limits concurrent calls across adapters", func(t *testing.T) {
+		var inFlight, maxInFlight atomic.Int32
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(tok, nil).Run(func(args mock.Arguments) {
+			n := inFlight.Add(1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			inFlight.Add(-1)
+		})
+
+		throttle := NewThrottle(2)
+		adapters := []Adapter{throttle.Wrap(mAdapter), throttle.Wrap(mAdapter), throttle.Wrap(mAdapter)}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = adapters[i%len(adapters)].Fetch(context.Background())
+			}()
+		}
+		wg.Wait()
+		assert.LessOrEqualf(t, maxInFlight.Load(), int32(2), "Fetch() max in flight")
+	})
+}
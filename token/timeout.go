@@ -0,0 +1,38 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NewTimeoutAdapter returns an Adapter failing calls to the inner Adapter that take longer than timeout, so each
+// adapter composed into a failover or other decorator can have its own deadline. The timeout is in addition to any
+// deadline of the context. A timeout of zero or less doesn't limit calls.
+func NewTimeoutAdapter(inner Adapter, timeout time.Duration) Adapter {
+	return timeoutAdapter{
+		inner:   inner,
+		timeout: timeout,
+	}
+}
+
+type timeoutAdapter struct {
+	inner   Adapter
+	timeout time.Duration
+}
+
+func (a timeoutAdapter) Fetch(ctx context.Context) (Token, error) {
+	if a.timeout <= 0 {
+		return a.inner.Fetch(ctx)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	t, err := a.inner.Fetch(tctx)
+	if err != nil && ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		return Token{}, fmt.Errorf("adapter timed out after %s: %w", a.timeout, err)
+	}
+	return t, err
+}
@@ -0,0 +1,71 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func Test_timeoutAdapter_Fetch(t *testing.T) {
+	tok := Token{AccessToken: "token-123"}
+
+	t.Run("inner adapter returns token within timeout, returns token", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.MatchedBy(func(ctx context.Context) bool {
+			_, ok := ctx.Deadline()
+			return ok
+		})).Return(tok, nil).Once()
+
+		got, err := NewTimeoutAdapter(mAdapter, time.Minute).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("inner adapter exceeds timeout, returns timeout error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, context.DeadlineExceeded).Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).Once()
+
+		_, err := NewTimeoutAdapter(mAdapter, time.Millisecond).Fetch(context.Background())
+		assert.ErrorIsf(t, err, context.DeadlineExceeded, "Fetch()")
+		assert.ErrorContainsf(t, err, "adapter timed out after 1ms", "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+
+	t.Run("context cancelled, returns error without timeout error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, context.Canceled).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := NewTimeoutAdapter(mAdapter, time.Minute).Fetch(ctx)
+		assert.ErrorIsf(t, err, context.Canceled, "Fetch()")
+		assert.NotContainsf(t, err.Error(), "timed out", "Fetch()")
+	})
+
+	t.Run("inner adapter returns error, returns error", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+
+		_, err := NewTimeoutAdapter(mAdapter, time.Minute).Fetch(context.Background())
+		assert.EqualErrorf(t, err, "error", "Fetch()")
+	})
+
+	t.Run("zero timeout, calls inner adapter without deadline", func(t *testing.T) {
+		mAdapter := new(mockAdapter)
+		mAdapter.On("Fetch", mock.MatchedBy(func(ctx context.Context) bool {
+			_, ok := ctx.Deadline()
+			return !ok
+		})).Return(tok, nil).Once()
+
+		got, err := NewTimeoutAdapter(mAdapter, 0).Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		mAdapter.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,210 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const bearerTokenType = "Bearer"
+
+// Token represents an access token
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	Scopes       []string  `json:"scope,omitempty"`
+	// Extra holds the string fields of the secret other than the token fields, e.g. a signing key stored alongside the
+	// token. It is nil if there are none.
+	Extra map[string]string `json:"-"`
+}
+
+// tokenFieldNames are the JSON names of the fields of a Token, which aren't held in Extra
+var tokenFieldNames = []string{"access_token", "token_type", "refresh_token", "expiry", "created_at", "scope"}
+
+// Valid reports whether the token can be used at now, i.e. it has an access token and either no expiry or an expiry
+// no earlier than buffer after now
+func (t Token) Valid(now time.Time, buffer time.Duration) bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || !t.Expiry.Before(now.Add(buffer)))
+}
+
+// Expired reports whether the token is past its expiry at now. Unlike Valid, it ignores any buffer before the expiry
+// and is false for tokens without an expiry.
+func (t Token) Expired(now time.Time) bool {
+	return !t.Expiry.IsZero() && t.Expiry.Before(now)
+}
+
+// SameCredential reports whether the token carries the same credential as other, ignoring metadata such as the expiry
+// and created dates which may change without the credential changing. The access tokens must match, as must the
+// refresh tokens if both tokens have one.
+func (t Token) SameCredential(other Token) bool {
+	if t.AccessToken != other.AccessToken {
+		return false
+	}
+	return t.RefreshToken == "" || other.RefreshToken == "" || t.RefreshToken == other.RefreshToken
+}
+
+// HasScopes reports whether the token was granted all the scopes
+func (t Token) HasScopes(scopes ...string) bool {
+	for _, s := range scopes {
+		if !slices.Contains(t.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthorizationHeader returns the value of an Authorization header carrying the token, "<TokenType> <AccessToken>". The
+// type defaults to Bearer if empty, and is normalised to Bearer for OAuth2 servers returning "bearer".
+func (t Token) AuthorizationHeader() string {
+	tokenType := strings.TrimSpace(t.TokenType)
+	if tokenType == "" || strings.EqualFold(tokenType, bearerTokenType) {
+		tokenType = bearerTokenType
+	}
+	return tokenType + " " + strings.TrimSpace(t.AccessToken)
+}
+
+// Clone returns a deep copy of the token. Fetchers return clones of their cached tokens, so callers can't mutate the
+// cached token through fields holding references.
+func (t Token) Clone() Token {
+	t.Scopes = slices.Clone(t.Scopes)
+	t.Extra = maps.Clone(t.Extra)
+	return t
+}
+
+// UnmarshalJSON parses a token, accepting expiry and created_at as either an RFC3339 string or Unix seconds, and scope
+// as either a space-delimited string or an array. Other string fields are held in Extra.
+func (t *Token) UnmarshalJSON(data []byte) error {
+	parsed, err := unmarshalToken(data, json.Unmarshal)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// unmarshalToken parses a token as UnmarshalJSON does, but with unmarshal, e.g. the Unmarshaler set by
+// WithJSONUnmarshaler, which would otherwise defer to UnmarshalJSON and so encoding/json
+func unmarshalToken(data []byte, unmarshal func(data []byte, v any) error) (Token, error) {
+	var f tokenFields
+	if err := unmarshal(data, &f); err != nil {
+		return Token{}, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := unmarshal(data, &fields); err != nil {
+		return Token{}, err
+	}
+
+	t := f.token()
+	for name, value := range fields {
+		var str string
+		if slices.Contains(tokenFieldNames, name) || unmarshal(value, &str) != nil {
+			continue
+		}
+		if t.Extra == nil {
+			t.Extra = make(map[string]string)
+		}
+		t.Extra[name] = str
+	}
+	return t, nil
+}
+
+// MarshalJSON formats a token, including the fields held in Extra alongside the token fields
+func (t Token) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(tokenAlias(t))
+	if err != nil || len(t.Extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range t.Extra {
+		if slices.Contains(tokenFieldNames, name) {
+			continue
+		}
+		if fields[name], err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// MarshalText formats a token as its JSON object, so it can be used as a single value by config libraries supporting
+// encoding.TextMarshaler. encoding/json still formats it as a nested object with MarshalJSON.
+func (t Token) MarshalText() ([]byte, error) {
+	return t.MarshalJSON()
+}
+
+// UnmarshalText parses a token from its JSON object, as formatted by MarshalText, e.g. a token held in an environment
+// variable
+func (t *Token) UnmarshalText(text []byte) error {
+	return t.UnmarshalJSON(text)
+}
+
+// tokenFields mirrors the JSON fields of a Token, with timestamps and scopes accepted in either supported format
+type tokenFields struct {
+	tokenAlias
+	Expiry    timestamp `json:"expiry,omitempty"`
+	CreatedAt timestamp `json:"created_at,omitempty"`
+	Scopes    scopes    `json:"scope,omitempty"`
+}
+
+// tokenAlias has the fields of Token without its methods, so it is parsed without recursing into UnmarshalJSON
+type tokenAlias Token
+
+func (f tokenFields) token() Token {
+	t := Token(f.tokenAlias)
+	t.Expiry = time.Time(f.Expiry)
+	t.CreatedAt = time.Time(f.CreatedAt)
+	t.Scopes = f.Scopes
+	return t
+}
+
+// scopes is a list of scopes that unmarshals from either a space-delimited string, as in OAuth 2.0 responses, or an
+// array
+type scopes []string
+
+func (s *scopes) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '"' {
+		return json.Unmarshal(data, (*[]string)(s))
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*s = strings.Fields(str)
+	return nil
+}
+
+// timestamp is a time.Time that unmarshals from either an RFC3339 string or a Unix seconds number
+type timestamp time.Time
+
+func (ts *timestamp) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] == '"' || string(data) == "null" {
+		return (*time.Time)(ts).UnmarshalJSON(data)
+	}
+
+	if secs, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		*ts = timestamp(time.Unix(secs, 0).UTC())
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid unix timestamp %s: %w", data, err)
+	}
+	secs, frac := math.Modf(f)
+	*ts = timestamp(time.Unix(int64(secs), int64(frac*float64(time.Second))).UTC())
+	return nil
+}
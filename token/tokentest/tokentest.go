@@ -0,0 +1,102 @@
+// Package tokentest provides fakes of the token fetcher and adapter for tests of code depending on them
+package tokentest
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-token-fetcher/token"
+	"sync"
+	"time"
+)
+
+// ErrNoResponse is returned by a fake with no responses scripted
+var ErrNoResponse = errors.New("no response scripted")
+
+// Response is the result of a single fetch from a fake
+type Response struct {
+	Token token.Token
+	Err   error
+}
+
+// Token returns a Response returning the token
+func Token(t token.Token) Response {
+	return Response{Token: t}
+}
+
+// Error returns a Response returning the error
+func Error(err error) Response {
+	return Response{Err: err}
+}
+
+// Expired returns a Response returning the token with an expiry date in the past
+func Expired(t token.Token) Response {
+	t.Expiry = time.Unix(0, 0).UTC()
+	return Response{Token: t}
+}
+
+// script returns scripted responses in order, repeating the last once all have been returned
+type script struct {
+	mu        sync.Mutex
+	responses []Response
+	calls     int
+}
+
+func (s *script) next() (token.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if len(s.responses) == 0 {
+		return token.Token{}, ErrNoResponse
+	}
+	r := s.responses[min(s.calls, len(s.responses))-1]
+	return r.Token.Clone(), r.Err
+}
+
+func (s *script) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// Fetcher is a fake token.TokenFetcher returning scripted responses, one per fetch in order, repeating the last once
+// all have been returned. It is safe for concurrent use.
+type Fetcher struct {
+	script script
+}
+
+// NewFetcher returns a Fetcher returning the responses
+func NewFetcher(responses ...Response) *Fetcher {
+	return &Fetcher{script: script{responses: responses}}
+}
+
+// Fetch returns the next scripted response. Fetch options are ignored.
+func (f *Fetcher) Fetch(_ context.Context, _ ...token.FetchOption) (token.Token, error) {
+	return f.script.next()
+}
+
+// Calls returns the number of fetches made
+func (f *Fetcher) Calls() int {
+	return f.script.count()
+}
+
+// Adapter is a fake token.Adapter returning scripted responses, one per fetch in order, repeating the last once all
+// have been returned, to test a real *token.Fetcher, e.g. its refresh behaviour. It is safe for concurrent use.
+type Adapter struct {
+	script script
+}
+
+// NewAdapter returns an Adapter returning the responses
+func NewAdapter(responses ...Response) *Adapter {
+	return &Adapter{script: script{responses: responses}}
+}
+
+// Fetch returns the next scripted response
+func (a *Adapter) Fetch(_ context.Context) (token.Token, error) {
+	return a.script.next()
+}
+
+// Calls returns the number of fetches made
+func (a *Adapter) Calls() int {
+	return a.script.count()
+}
@@ -0,0 +1,76 @@
+package tokentest
+
+import (
+	"context"
+	"errors"
+	"github.com/ellogroup/ello-golang-token-fetcher/token"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var _ token.TokenFetcher = (*Fetcher)(nil)
+var _ token.Adapter = (*Adapter)(nil)
+
+func TestFetcher_Fetch(t *testing.T) {
+	tok := token.Token{AccessToken: "token-123"}
+	err := errors.New("error")
+
+	type want struct {
+		token token.Token
+		err   error
+	}
+	tests := []struct {
+		name      string
+		responses []Response
+		want      []want
+	}{
+		{
+			name: "no responses, returns no response error",
+			want: []want{{err: ErrNoResponse}, {err: ErrNoResponse}},
+		},
+		{
+			name:      "responses scripted, returns responses in order",
+			responses: []Response{Error(err), Token(tok)},
+			want:      []want{{err: err}, {token: tok}},
+		},
+		{
+			name:      "responses exhausted, repeats last response",
+			responses: []Response{Token(tok), Error(err)},
+			want:      []want{{token: tok}, {err: err}, {err: err}},
+		},
+		{
+			name:      "expired response, returns token with expiry in the past",
+			responses: []Response{Expired(tok)},
+			want:      []want{{token: token.Token{AccessToken: "token-123", Expiry: time.Unix(0, 0).UTC()}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFetcher(tt.responses...)
+			for i, w := range tt.want {
+				got, err := f.Fetch(context.Background())
+				assert.ErrorIsf(t, err, w.err, "Fetch() call %d", i)
+				assert.Equalf(t, w.token, got, "Fetch() call %d", i)
+			}
+			assert.Equalf(t, len(tt.want), f.Calls(), "Calls()")
+		})
+	}
+}
+
+func TestAdapter_Fetch(t *testing.T) {
+	t.Run("adapter returns expired token then valid token, fetcher refreshes expired token", func(t *testing.T) {
+		tok := token.Token{AccessToken: "token-123"}
+		a := NewAdapter(Expired(tok), Token(tok))
+		f := token.New(a)
+
+		got, err := f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Truef(t, got.Expired(time.Now()), "Fetch() expired")
+
+		got, err = f.Fetch(context.Background())
+		assert.NoErrorf(t, err, "Fetch()")
+		assert.Equalf(t, tok, got, "Fetch()")
+		assert.Equalf(t, 2, a.Calls(), "Calls()")
+	})
+}
@@ -0,0 +1,78 @@
+package token
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-clock/clock"
+)
+
+const refreshSpanName = "token.refresh"
+
+// Tracer starts spans around adapter calls. It mirrors the subset of OpenTelemetry used by the Fetcher so that tracing
+// can be enabled without this package depending on a tracing library.
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span is a single traced operation started by a Tracer
+type Span interface {
+	SetAttribute(key string, value any)
+	SetError(err error)
+	End()
+}
+
+// WithTracer sets the Tracer used to start a span around each adapter call
+func WithTracer(tracer Tracer) Option {
+	return func(c *config) { c.tracer = tracer }
+}
+
+// adapterCall calls the adapter with copies of the fields of the config it reads, so it can be made without the Fetcher
+// locked
+type adapterCall struct {
+	adapter       Adapter
+	clock         clock.Clock
+	tracer        Tracer
+	name          string
+	postProcessor func(t Token) (Token, error)
+}
+
+// adapterCall returns an adapterCall for the Fetcher. It must be called while the Fetcher is locked.
+func (f *Fetcher) adapterCall() adapterCall {
+	return adapterCall{
+		adapter:       f.adapter,
+		clock:         f.clock,
+		tracer:        f.config.tracer,
+		name:          f.config.name,
+		postProcessor: f.config.postProcessor,
+	}
+}
+
+// fetchFromAdapter fetches a token from the adapter. It must be called while the Fetcher is locked.
+func (f *Fetcher) fetchFromAdapter(ctx context.Context) (Token, error) {
+	return f.adapterCall().fetch(ctx)
+}
+
+func (a adapterCall) fetch(ctx context.Context) (Token, error) {
+	if a.tracer == nil {
+		t, err := a.adapter.Fetch(ctx)
+		return postProcess(a.postProcessor, t, err)
+	}
+
+	ctx, span := a.tracer.Start(ctx, refreshSpanName)
+	defer span.End()
+	if a.name != "" {
+		span.SetAttribute("token.fetcher.name", a.name)
+	}
+
+	start := a.clock.Now()
+	t, err := a.adapter.Fetch(ctx)
+	t, err = postProcess(a.postProcessor, t, err)
+	span.SetAttribute("token.refresh.duration_ms", a.clock.Since(start).Milliseconds())
+	if err != nil {
+		span.SetAttribute("token.refresh.outcome", "error")
+		span.SetError(err)
+		return Token{}, err
+	}
+
+	span.SetAttribute("token.refresh.outcome", "success")
+	return t, nil
+}
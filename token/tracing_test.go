@@ -0,0 +1,160 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type mockTracer struct {
+	mock.Mock
+}
+
+func (m *mockTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	args := m.Called(ctx, spanName)
+	return args.Get(0).(context.Context), args.Get(1).(Span)
+}
+
+type mockSpan struct {
+	mock.Mock
+}
+
+func (m *mockSpan) SetAttribute(key string, value any) {
+	m.Called(key, value)
+}
+
+func (m *mockSpan) SetError(err error) {
+	m.Called(err)
+}
+
+func (m *mockSpan) End() {
+	m.Called()
+}
+
+func TestFetcher_fetchFromAdapter(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+	adapterErr := errors.New("error")
+
+	type spanCtxKey struct{}
+	spanCtx := context.WithValue(context.Background(), spanCtxKey{}, "span")
+
+	type args struct {
+		ctx context.Context
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+		span    func(m *mockSpan)
+	}
+	tests := []struct {
+		name       string
+		withTracer bool
+		fetcher    string
+		args       args
+		mockOpts   mockOpts
+		want       Token
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name: "no tracer, adapter returns token, returns token",
+			args: args{context.Background()},
+			mockOpts: mockOpts{
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:       "tracer set, adapter returns token, records success and returns token",
+			withTracer: true,
+			args:       args{context.Background()},
+			mockOpts: mockOpts{
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", spanCtx).Return(tok, nil).Once()
+				},
+				span: func(m *mockSpan) {
+					m.On("SetAttribute", "token.refresh.duration_ms", int64(0)).Once()
+					m.On("SetAttribute", "token.refresh.outcome", "success").Once()
+					m.On("End").Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:       "tracer set, fetcher named, records name and returns token",
+			withTracer: true,
+			fetcher:    "service-a",
+			args:       args{context.Background()},
+			mockOpts: mockOpts{
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", spanCtx).Return(tok, nil).Once()
+				},
+				span: func(m *mockSpan) {
+					m.On("SetAttribute", "token.fetcher.name", "service-a").Once()
+					m.On("SetAttribute", "token.refresh.duration_ms", int64(0)).Once()
+					m.On("SetAttribute", "token.refresh.outcome", "success").Once()
+					m.On("End").Once()
+				},
+			},
+			want:    tok,
+			wantErr: assert.NoError,
+		},
+		{
+			name:       "tracer set, adapter returns error, records error and returns error",
+			withTracer: true,
+			args:       args{context.Background()},
+			mockOpts: mockOpts{
+				adapter: func(m *mockAdapter) {
+					m.On("Fetch", spanCtx).Return(Token{}, adapterErr).Once()
+				},
+				span: func(m *mockSpan) {
+					m.On("SetAttribute", "token.refresh.duration_ms", int64(0)).Once()
+					m.On("SetAttribute", "token.refresh.outcome", "error").Once()
+					m.On("SetError", adapterErr).Once()
+					m.On("End").Once()
+				},
+			},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+			mSpan := new(mockSpan)
+			if tt.mockOpts.span != nil {
+				tt.mockOpts.span(mSpan)
+			}
+
+			f := &Fetcher{
+				config:  config{name: tt.fetcher},
+				clock:   clock.NewFixed(now),
+				adapter: mAdapter,
+			}
+			if tt.withTracer {
+				mTracer := new(mockTracer)
+				mTracer.On("Start", tt.args.ctx, "token.refresh").Return(spanCtx, mSpan).Once()
+				f.config.tracer = mTracer
+				defer mTracer.AssertExpectations(t)
+			}
+
+			got, err := f.fetchFromAdapter(tt.args.ctx)
+			mAdapter.AssertExpectations(t)
+			mSpan.AssertExpectations(t)
+			if !tt.wantErr(t, err, fmt.Sprintf("fetchFromAdapter(%v)", tt.args.ctx)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "fetchFromAdapter(%v)", tt.args.ctx)
+		})
+	}
+}
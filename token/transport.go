@@ -0,0 +1,157 @@
+package token
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that adds an access token from a TokenFetcher to each request
+type Transport struct {
+	fetcher TokenFetcher
+	base    http.RoundTripper
+	config  transportConfig
+}
+
+type transportConfig struct {
+	headerName  string
+	scheme      string
+	tokenScheme bool
+	retryOn401  bool
+}
+
+var defaultTransportConfig = transportConfig{
+	headerName:  "Authorization",
+	tokenScheme: true,
+}
+
+type TransportOption func(*transportConfig)
+
+// WithAuthHeaderName sets the name of the request header the access token is added to
+func WithAuthHeaderName(name string) TransportOption {
+	return func(c *transportConfig) { c.headerName = name }
+}
+
+// WithAuthScheme sets the scheme preceding the access token in the request header, overriding the token type. An empty
+// scheme adds the access token alone.
+func WithAuthScheme(scheme string) TransportOption {
+	return func(c *transportConfig) {
+		c.scheme = scheme
+		c.tokenScheme = false
+	}
+}
+
+// WithRetryOn401 resends a request rejected with 401 Unauthorized once with a fresh token, invalidating the rejected
+// token if the fetcher supports InvalidateIf, otherwise forcing a refresh. Request bodies that can't be rewound with
+// GetBody are buffered in memory so they can be resent. If a fresh token can't be fetched, the 401 response is returned.
+func WithRetryOn401() TransportOption {
+	return func(c *transportConfig) { c.retryOn401 = true }
+}
+
+// NewTransport returns a new Transport adding access tokens from the TokenFetcher to requests sent by the base
+// http.RoundTripper, or http.DefaultTransport if base is nil
+func NewTransport(fetcher TokenFetcher, base http.RoundTripper, opts ...TransportOption) *Transport {
+	c := defaultTransportConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		fetcher: fetcher,
+		base:    base,
+		config:  c,
+	}
+}
+
+// HTTPClient returns a copy of the base http.Client, or a new http.Client if base is nil, whose transport adds access
+// tokens from the Fetcher to each request, wrapping the transport of base, or http.DefaultTransport if it has none
+func (f *Fetcher) HTTPClient(base *http.Client, opts ...TransportOption) *http.Client {
+	var c http.Client
+	if base != nil {
+		c = *base
+	}
+	c.Transport = NewTransport(f, c.Transport, opts...)
+	return &c
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.fetcher.Fetch(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, fmt.Errorf("unable to fetch token for request: %w", err)
+	}
+
+	// RoundTrippers must not modify the original request
+	r := req.Clone(req.Context())
+	if t.config.retryOn401 {
+		if err := bufferBody(r); err != nil {
+			return nil, fmt.Errorf("unable to buffer request body: %w", err)
+		}
+	}
+	r.Header.Set(t.config.headerName, t.headerValue(tok))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil || !t.config.retryOn401 || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	return t.retry(r, resp, tok)
+}
+
+// retry resends a request rejected with 401 Unauthorized with a fresh token, returning the rejected response if a fresh
+// token can't be fetched or the body can't be rewound
+func (t *Transport) retry(r *http.Request, resp *http.Response, rejected Token) (*http.Response, error) {
+	var opts []FetchOption
+	if f, ok := t.fetcher.(interface{ InvalidateIf(t Token) bool }); ok {
+		f.InvalidateIf(rejected)
+	} else {
+		opts = append(opts, ForceRefresh())
+	}
+	tok, err := t.fetcher.Fetch(r.Context(), opts...)
+	if err != nil {
+		return resp, nil
+	}
+
+	retry := r.Clone(r.Context())
+	if r.GetBody != nil {
+		if retry.Body, err = r.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	retry.Header.Set(t.config.headerName, t.headerValue(tok))
+	return t.base.RoundTrip(retry)
+}
+
+// bufferBody reads the body of the request into memory if it can't be rewound with GetBody, so it can be resent
+func bufferBody(r *http.Request) error {
+	if r.Body == nil || r.Body == http.NoBody || r.GetBody != nil {
+		return nil
+	}
+
+	b, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	return nil
+}
+
+// headerValue returns the header carrying the token, using the token type as the scheme unless a scheme is set
+func (t *Transport) headerValue(tok Token) string {
+	if t.config.tokenScheme {
+		return tok.AuthorizationHeader()
+	}
+	if t.config.scheme == "" {
+		return tok.AccessToken
+	}
+	return t.config.scheme + " " + tok.AccessToken
+}
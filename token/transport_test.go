@@ -0,0 +1,339 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-clock/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewTransport(t *testing.T) {
+	f := &Fetcher{}
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) { return nil, nil })
+
+	type args struct {
+		base http.RoundTripper
+		opts []TransportOption
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantConfig transportConfig
+		wantBase   http.RoundTripper
+	}{
+		{
+			name:       "NewTransport returns new transport with default values",
+			args:       args{},
+			wantConfig: transportConfig{headerName: "Authorization", tokenScheme: true},
+			wantBase:   http.DefaultTransport,
+		},
+		{
+			name: "NewTransport returns new transport with provided options",
+			args: args{
+				base: base,
+				opts: []TransportOption{
+					WithAuthHeaderName("X-Api-Token"),
+					WithAuthScheme(""),
+					WithRetryOn401(),
+				},
+			},
+			wantConfig: transportConfig{headerName: "X-Api-Token", scheme: "", retryOn401: true},
+			wantBase:   base,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTransport(f, tt.args.base, tt.args.opts...)
+			assert.Equalf(t, tt.wantConfig, got.config, "NewTransport(%v, %v)", tt.args.base, tt.args.opts)
+			assert.Equalf(t, fmt.Sprintf("%p", tt.wantBase), fmt.Sprintf("%p", got.base), "NewTransport(%v, %v)", tt.args.base, tt.args.opts)
+			assert.Samef(t, f, got.fetcher, "NewTransport(%v, %v)", tt.args.base, tt.args.opts)
+		})
+	}
+}
+
+func TestFetcher_HTTPClient(t *testing.T) {
+	f := &Fetcher{}
+	baseTransport := roundTripperFunc(func(req *http.Request) (*http.Response, error) { return nil, nil })
+
+	tests := []struct {
+		name        string
+		base        *http.Client
+		opts        []TransportOption
+		wantTimeout time.Duration
+		wantBase    http.RoundTripper
+		wantConfig  transportConfig
+	}{
+		{
+			name:       "base client nil, returns client wrapping default transport",
+			wantBase:   http.DefaultTransport,
+			wantConfig: transportConfig{headerName: "Authorization", tokenScheme: true},
+		},
+		{
+			name:        "base client without transport, returns copy of client wrapping default transport",
+			base:        &http.Client{Timeout: time.Second},
+			wantTimeout: time.Second,
+			wantBase:    http.DefaultTransport,
+			wantConfig:  transportConfig{headerName: "Authorization", tokenScheme: true},
+		},
+		{
+			name:        "base client with transport and options, returns copy of client wrapping base transport",
+			base:        &http.Client{Timeout: time.Second, Transport: baseTransport},
+			opts:        []TransportOption{WithAuthHeaderName("X-Api-Token")},
+			wantTimeout: time.Second,
+			wantBase:    baseTransport,
+			wantConfig:  transportConfig{headerName: "X-Api-Token", tokenScheme: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original http.RoundTripper
+			if tt.base != nil {
+				original = tt.base.Transport
+			}
+
+			got := f.HTTPClient(tt.base, tt.opts...)
+			assert.NotSamef(t, tt.base, got, "HTTPClient(%v)", tt.base)
+			assert.Equalf(t, tt.wantTimeout, got.Timeout, "HTTPClient(%v)", tt.base)
+			transport, ok := got.Transport.(*Transport)
+			if !assert.Truef(t, ok, "HTTPClient(%v) transport type", tt.base) {
+				return
+			}
+			assert.Equalf(t, fmt.Sprintf("%p", tt.wantBase), fmt.Sprintf("%p", transport.base), "HTTPClient(%v)", tt.base)
+			assert.Equalf(t, tt.wantConfig, transport.config, "HTTPClient(%v)", tt.base)
+			assert.Samef(t, f, transport.fetcher, "HTTPClient(%v)", tt.base)
+			if tt.base != nil {
+				assert.Equalf(t, fmt.Sprintf("%p", original), fmt.Sprintf("%p", tt.base.Transport), "HTTPClient(%v) base unchanged", tt.base)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tok := Token{AccessToken: "token-123"}
+
+	type fields struct {
+		config transportConfig
+	}
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		mockOpts   mockOpts
+		wantHeader http.Header
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "default config, adds bearer authorization header",
+			fields: fields{config: defaultTransportConfig},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			wantHeader: http.Header{"Authorization": {"Bearer token-123"}},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "default config, token with type, adds authorization header with token type",
+			fields: fields{config: defaultTransportConfig},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", TokenType: "DPoP"}, nil).Once()
+			}},
+			wantHeader: http.Header{"Authorization": {"DPoP token-123"}},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "basic scheme, adds basic authorization header",
+			fields: fields{config: transportConfig{headerName: "Authorization", scheme: "Basic"}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			wantHeader: http.Header{"Authorization": {"Basic token-123"}},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "custom header and no scheme, adds custom header with token",
+			fields: fields{config: transportConfig{headerName: "X-Api-Token"}},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(tok, nil).Once()
+			}},
+			wantHeader: http.Header{"X-Api-Token": {"token-123"}},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "fetcher returns error, returns error",
+			fields: fields{config: defaultTransportConfig},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			var gotHeader http.Header
+			tr := &Transport{
+				fetcher: &Fetcher{config: defaultConfig, clock: clock.NewFixed(now), adapter: mAdapter},
+				base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					gotHeader = req.Header
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				}),
+				config: tt.fields.config,
+			}
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://example.com", nil)
+			_, err := tr.RoundTrip(req)
+			if !tt.wantErr(t, err, "RoundTrip()") {
+				return
+			}
+			assert.Equalf(t, tt.wantHeader, gotHeader, "RoundTrip()")
+			assert.Emptyf(t, req.Header, "RoundTrip() modified original request")
+		})
+	}
+}
+
+// forceRefreshFetcher is a TokenFetcher without InvalidateIf, returning a new token on a forced refresh
+type forceRefreshFetcher struct {
+	forced int
+}
+
+func (f *forceRefreshFetcher) Fetch(_ context.Context, opts ...FetchOption) (Token, error) {
+	var o fetchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.forceRefresh {
+		f.forced++
+		return Token{AccessToken: "token-456"}, nil
+	}
+	return Token{AccessToken: "token-123"}, nil
+}
+
+func TestTransport_RoundTrip_retryOn401(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	type mockOpts struct {
+		adapter func(m *mockAdapter)
+	}
+	tests := []struct {
+		name        string
+		config      transportConfig
+		statuses    []int
+		mockOpts    mockOpts
+		wantStatus  int
+		wantHeaders []string
+	}{
+		{
+			name:     "request rejected, retries once with fresh token",
+			config:   transportConfig{headerName: "Authorization", tokenScheme: true, retryOn401: true},
+			statuses: []int{http.StatusUnauthorized, http.StatusOK},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-456"}, nil).Once()
+			}},
+			wantStatus:  http.StatusOK,
+			wantHeaders: []string{"Bearer token-123", "Bearer token-456"},
+		},
+		{
+			name:     "request rejected twice, returns rejected response after one retry",
+			config:   transportConfig{headerName: "Authorization", tokenScheme: true, retryOn401: true},
+			statuses: []int{http.StatusUnauthorized, http.StatusUnauthorized},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-456"}, nil).Once()
+			}},
+			wantStatus:  http.StatusUnauthorized,
+			wantHeaders: []string{"Bearer token-123", "Bearer token-456"},
+		},
+		{
+			name:     "request rejected, fresh token can't be fetched, returns rejected response",
+			config:   transportConfig{headerName: "Authorization", tokenScheme: true, retryOn401: true},
+			statuses: []int{http.StatusUnauthorized},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+				m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+			}},
+			wantStatus:  http.StatusUnauthorized,
+			wantHeaders: []string{"Bearer token-123"},
+		},
+		{
+			name:     "retry on 401 not set, request rejected, returns rejected response",
+			config:   defaultTransportConfig,
+			statuses: []int{http.StatusUnauthorized},
+			mockOpts: mockOpts{func(m *mockAdapter) {
+				m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+			}},
+			wantStatus:  http.StatusUnauthorized,
+			wantHeaders: []string{"Bearer token-123"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mAdapter := new(mockAdapter)
+			if tt.mockOpts.adapter != nil {
+				tt.mockOpts.adapter(mAdapter)
+			}
+
+			var gotHeaders, gotBodies []string
+			tr := &Transport{
+				fetcher: &Fetcher{config: defaultConfig, clock: clock.NewFixed(now), adapter: mAdapter},
+				base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					body, _ := io.ReadAll(req.Body)
+					gotHeaders = append(gotHeaders, req.Header.Get("Authorization"))
+					gotBodies = append(gotBodies, string(body))
+					status := tt.statuses[len(gotHeaders)-1]
+					return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+				}),
+				config: tt.config,
+			}
+			// A body without GetBody, which must be buffered to be resent
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "https://example.com", io.NopCloser(strings.NewReader("payload")))
+			resp, err := tr.RoundTrip(req)
+			assert.NoErrorf(t, err, "RoundTrip()")
+			assert.Equalf(t, tt.wantStatus, resp.StatusCode, "RoundTrip()")
+			assert.Equalf(t, tt.wantHeaders, gotHeaders, "RoundTrip()")
+			for _, body := range gotBodies {
+				assert.Equalf(t, "payload", body, "RoundTrip() body")
+			}
+			mAdapter.AssertExpectations(t)
+		})
+	}
+
+	t.Run("fetcher without InvalidateIf, request rejected, retries with forced refresh", func(t *testing.T) {
+		f := &forceRefreshFetcher{}
+		var gotHeaders []string
+		tr := NewTransport(f, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			gotHeaders = append(gotHeaders, req.Header.Get("Authorization"))
+			status := http.StatusOK
+			if len(gotHeaders) == 1 {
+				status = http.StatusUnauthorized
+			}
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}), WithRetryOn401())
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://example.com", nil)
+		resp, err := tr.RoundTrip(req)
+		assert.NoErrorf(t, err, "RoundTrip()")
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "RoundTrip()")
+		assert.Equalf(t, []string{"Bearer token-123", "Bearer token-456"}, gotHeaders, "RoundTrip()")
+		assert.Equalf(t, 1, f.forced, "RoundTrip() forced refreshes")
+	})
+}
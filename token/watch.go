@@ -0,0 +1,43 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Watch polls the adapter every interval, refreshing the cached token, and returns a channel receiving the token each
+// time its credential changes, as reported by SameCredential, starting with the first token fetched. It suits sources
+// without a reliable expiry date, such as files. Failed polls are skipped. The channel is closed once ctx is done or the
+// Fetcher is closed. The interval must be positive.
+func (f *Fetcher) Watch(ctx context.Context, interval time.Duration) <-chan Token {
+	ch := make(chan Token)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last Token
+		for {
+			t, err := f.Fetch(ctx, ForceRefresh())
+			if errors.Is(err, ErrFetcherClosed) {
+				return
+			}
+			if err == nil && !t.SameCredential(last) {
+				last = t
+				select {
+				case ch <- t:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
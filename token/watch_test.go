@@ -0,0 +1,57 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestFetcher_Watch(t *testing.T) {
+	t.Run("adapter returns changed tokens, emits token on each credential change", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil).Once()
+		m.On("Fetch", mock.Anything).Return(Token{}, errors.New("error")).Once()
+		m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123", Expiry: time.Now().Add(time.Hour)}, nil).Once()
+		m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-456"}, nil)
+		f := New(m)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := f.Watch(ctx, time.Millisecond)
+
+		assert.Equalf(t, Token{AccessToken: "token-123"}, <-ch, "Watch() first token")
+		assert.Equalf(t, Token{AccessToken: "token-456"}, <-ch, "Watch() changed token")
+
+		cancel()
+		for range ch {
+			// Drain tokens sent before the watcher stopped
+		}
+	})
+
+	t.Run("context cancelled, closes channel", func(t *testing.T) {
+		m := new(mockAdapter)
+		m.On("Fetch", mock.Anything).Return(Token{AccessToken: "token-123"}, nil)
+		f := New(m)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := f.Watch(ctx, time.Hour)
+		assert.Equalf(t, Token{AccessToken: "token-123"}, <-ch, "Watch() first token")
+
+		cancel()
+		_, ok := <-ch
+		assert.Falsef(t, ok, "Watch() channel open")
+	})
+
+	t.Run("fetcher closed, closes channel", func(t *testing.T) {
+		m := new(mockAdapter)
+		f := New(m)
+		assert.NoErrorf(t, f.Close(), "Close()")
+
+		_, ok := <-f.Watch(context.Background(), time.Millisecond)
+		assert.Falsef(t, ok, "Watch() channel open")
+		m.AssertExpectations(t)
+	})
+}